@@ -2,25 +2,34 @@ package cobra
 
 import (
 	"context"
-	"cronos-tools/src/utils"
-	"encoding/hex"
+	"cronos-tools/src/journal"
+	"cronos-tools/src/txengine"
 	"errors"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/shopspring/decimal"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/cobra"
 	"log"
-	"strings"
-	"time"
+	"sync"
 )
 
-func asyncMint(cmd *cobra.Command) {
-	mnemonic, err := cmd.Flags().GetString("mnemonic")
+// accountMintResult 记录单个账户在并发mint中的结果
+type accountMintResult struct {
+	AccountIndex uint
+	Address      common.Address
+	Succeeded    uint
+	Failed       uint
+	Err          error
+}
+
+// asyncMint mints with one worker per derived account, at most concurrency workers
+// running at the same time, and blocks until every worker has finished.
+func asyncMint(cmd *cobra.Command, jobJournal *journal.Journal, progress map[uint]*journal.AccountProgress) {
+	keys, err := getKeySource(cmd)
 	if err != nil {
-		log.Panicln(errors.New("mnemonic is required"))
+		log.Panicln(err)
 	}
-	if mnemonic == "" {
-		log.Panicln(errors.New("mnemonic is required"))
+	if err := checkResumedAddresses(keys, progress); err != nil {
+		log.Panicln(err)
 	}
 	rpc, err := cmd.Flags().GetString("rpc")
 	if err != nil {
@@ -43,19 +52,10 @@ func asyncMint(cmd *cobra.Command) {
 		log.Panicln(errors.New("start-index must less than or equal to end-index"))
 	}
 
-	hexContent, err := cmd.Flags().GetString("hex-content")
-	if err != nil {
-		log.Panicln(errors.New("hex-content is required"))
-	}
-	hexContent = strings.TrimPrefix(hexContent, "0x")
-	textContent, err := cmd.Flags().GetString("text-content")
+	payload, err := getMintPayload(cmd)
 	if err != nil {
-		log.Panicln(errors.New("text-content is required"))
-	}
-	if hexContent == "" && textContent == "" {
-		log.Panicln(errors.New("hex-content or text-content is required"))
+		log.Panicln(err)
 	}
-	useHexContent := hexContent != ""
 
 	perAddressMinted, err := cmd.Flags().GetUint("per-address-minted")
 	if err != nil {
@@ -65,156 +65,123 @@ func asyncMint(cmd *cobra.Command) {
 		log.Panicln(errors.New("per-address-minted must bigger than 0"))
 	}
 
-	client, err := ethclient.Dial(rpc)
+	concurrency, err := cmd.Flags().GetUint("concurrency")
+	if err != nil {
+		log.Panicln(errors.New("concurrency is required"))
+	}
+	if concurrency == 0 {
+		log.Panicln(errors.New("concurrency must bigger than 0"))
+	}
+
+	client, err := dialRPC(cmd, rpc)
 	if err != nil {
 		log.Panicln(err)
 	}
+	// 同时发送到所有节点，抢先进入mempool
+	if client.FanOut, err = cmd.Flags().GetBool("broadcast"); err != nil {
+		log.Panicln(err)
+	}
 
 	networkID, err := client.NetworkID(context.Background())
 	if err != nil {
 		log.Panicln(err)
 	}
-	gasLimit := uint64(22000)
-
-	for i := startIndex; i <= endIndex; i++ {
-		go func(accountIndex uint) {
-			// 获取当前账户的私钥
-			accountPrivateKey := utils.GetPrivateKey(mnemonic, accountIndex)
-			// 获取当前账户的地址
-			accountAddress := utils.GetAddressFromPrivateKey(accountPrivateKey)
-			// 获取当前账户的nonce
-			localNonce, err := client.PendingNonceAt(context.Background(), accountAddress)
-			if err != nil {
-				log.Println("Can not get nonce ", err)
-				// 如果获取nonce失败，则等待10秒后
-				for j := 0; j < 5; j++ {
-					time.Sleep(10 * time.Second)
-					localNonce, err = client.PendingNonceAt(context.Background(), accountAddress)
-					if err == nil {
-						continue
-					}
-				}
-				if err != nil {
-					log.Panicln("Can not get nonce after retry 5 times ", err)
-				}
-			}
-			for j := uint(0); j < perAddressMinted; j++ {
-				// 获取当前账户的gasPrice
-				gasPrice, err := client.SuggestGasPrice(context.Background())
-				if err != nil {
-					log.Println("Can not get gas price ", err)
-					// 如果获取gasPrice失败，则等待10秒后
-					for x := 0; x < 5; x++ {
-						time.Sleep(10 * time.Second)
-						gasPrice, err = client.SuggestGasPrice(context.Background())
-						if err == nil {
-							continue
-						}
-					}
-					if err != nil {
-						log.Panicln("Can not get gas price after retry 5 times ", err)
-					}
-				}
-				bufferedGasPrice := decimal.NewFromBigInt(gasPrice, 0).Mul(decimal.NewFromFloat32(1)).BigInt()
-				// 构造payload
-				var payload []byte
-				if useHexContent {
-					payload, err = hex.DecodeString(hexContent)
-					if err != nil {
-						log.Panicln(err)
-					}
-				} else {
-					payload = []byte(textContent)
-				}
-
-				// 检查当前账户的native coin余额是否足够支付gas fee
-				balance, err := client.BalanceAt(context.Background(), accountAddress, nil)
-				if err != nil {
-					log.Println("Can not get balance", err)
-					// 如果获取balance失败，则等待10秒后
-					for y := 0; y < 5; y++ {
-						log.Println("Retry get balance")
-						time.Sleep(10 * time.Second)
-						balance, err = client.BalanceAt(context.Background(), accountAddress, nil)
-						if err == nil {
-							log.Println("Shutdown minting for account " + accountAddress.Hex() + ",Can not get balance after retry " + string(rune(y)) + " times")
-							return
-						}
-					}
-					if err != nil {
-						log.Panicln("Can not get balance after retry 5 times ", err)
-					}
-				}
-
-				// 计算gas fee
-				gasFee := decimal.NewFromBigInt(bufferedGasPrice, 0).Mul(decimal.NewFromInt(int64(gasLimit))).BigInt()
-				if balance.Cmp(gasFee) < 0 {
-					log.Println("Account " + accountAddress.Hex() + " balance is not enough to pay for gas fee")
-					log.Println("Switch to next account")
-					break
-				}
+	nonceManager, err := getNonceManager(cmd, client)
+	if err != nil {
+		log.Panicln(err)
+	}
+	feeConfig, err := getFeeConfig(cmd)
+	if err != nil {
+		log.Panicln(err)
+	}
+	gasConfig, err := getGasConfig(cmd)
+	if err != nil {
+		log.Panicln(err)
+	}
 
-				// 构造交易
-				tx := types.NewTx(&types.LegacyTx{
-					Nonce:    localNonce,
-					To:       &accountAddress,
-					Value:    decimal.Zero.BigInt(),
-					Gas:      gasLimit,
-					GasPrice: bufferedGasPrice,
-					Data:     payload,
-				})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if !startMintCap(ctx, cancel, cmd, client, payload) {
+		return
+	}
+	stream := newTxStream(cmd)
+	run := &mintRun{
+		payload:          payload,
+		perAddressMinted: perAddressMinted,
+		tracker:          newTracker(context.Background(), client, jobJournal, stream),
+		client:           client,
+		journal:          jobJournal,
+		stream:           stream,
+		progress:         progress,
+	}
+	results := runMintWorkers(ctx, startIndex, endIndex, concurrency, func(accountIndex uint, result *accountMintResult) {
+		// 获取当前账户的私钥
+		accountPrivateKey, err := keys.PrivateKey(accountIndex)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		sender := txengine.NewSender(client, networkID, accountPrivateKey)
+		sender.Retry = client.Retry
+		sender.Gas = gasConfig
+		sender.Nonces = nonceManager
+		sender.Fees = feeConfig
+		result.Address = sender.Address()
+		result.Succeeded, result.Failed, result.Err = run.mintAccount(ctx, sender, accountIndex)
+	})
+	accountIndexes, _, _ := logMintResults(results)
+	waitConfirmations(cmd, run.tracker, accountIndexes)
+	stream.flush()
+	logBroadcastStats(client)
+	log.Println("Mint finished")
+}
 
-				// 签名交易
-				signedTx, err := types.SignTx(tx, types.NewEIP155Signer(networkID), accountPrivateKey)
-				if err != nil {
-					log.Panicln("Can not sign transaction ", err)
-				}
-				// 发送交易
-				err = client.SendTransaction(context.Background(), signedTx)
-				if err != nil {
-					if strings.Contains(err.Error(), "invalid sequence") {
-						time.Sleep(3 * time.Second)
-						j--
-						continue
-					}
-					if strings.Contains(err.Error(), "tx already in mempool") {
-						continue
-					}
-					if strings.Contains(err.Error(), "insufficient funds") {
-						log.Println("Account " + accountAddress.Hex() + " balance is not enough to pay for gas fee")
-						return
-					}
-					log.Panicln(err)
+// runMintWorkers runs mint for every account from startIndex to endIndex, at most concurrency at
+// the same time, and stops starting new accounts once ctx is done. A panicking worker only fails
+// its own account.
+func runMintWorkers(ctx context.Context, startIndex uint, endIndex uint, concurrency uint, mint func(accountIndex uint, result *accountMintResult)) []*accountMintResult {
+	results := make([]*accountMintResult, 0, endIndex-startIndex+1)
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := startIndex; i <= endIndex; i++ {
+		// 等到有空闲的worker后再检查，等待期间可能已达到mint进度上限
+		semaphore <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		result := &accountMintResult{AccountIndex: i}
+		results = append(results, result)
+		wg.Add(1)
+		go func(accountIndex uint, result *accountMintResult) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			// 单个账户panic时只记录失败，不影响其他账户
+			defer func() {
+				if r := recover(); r != nil {
+					result.Err = fmt.Errorf("panic: %v", r)
+					log.Println("Account index: ", accountIndex, " Address: ", result.Address.Hex(), " Worker panic: ", r)
 				}
+			}()
+			mint(accountIndex, result)
+		}(i, result)
+	}
+	wg.Wait()
+	return results
+}
 
-				txHash := signedTx.Hash()
-				txHashString := txHash.Hex()
-
-				log.Println("Account index: ", accountIndex, " Address: ", accountAddress.Hex(), " Tx hash: ", txHashString, " Payload: ", string(payload))
-
-				time.Sleep(3 * time.Second)
-				localNonce++
-				retryTimes := 0
-				maxRetryTimes := 10
-				for {
-					remoteNonce, err := client.PendingNonceAt(context.Background(), accountAddress)
-					if err != nil {
-						log.Panicln(err)
-					}
-					if remoteNonce == localNonce {
-						break
-					} else {
-						time.Sleep(5 * time.Second)
-						retryTimes++
-						if retryTimes > maxRetryTimes {
-							log.Println("Can not get remote nonce after retry " + string(rune(maxRetryTimes)) + " times")
-							log.Println("Switch to next account")
-							return
-						}
-					}
-				}
-			}
-		}(i)
+// logMintResults 汇总并打印每个账户的结果，返回地址对应的账户序号和发送成功、失败的总数
+func logMintResults(results []*accountMintResult) (accountIndexes map[common.Address]uint, totalSucceeded uint, totalFailed uint) {
+	accountIndexes = make(map[common.Address]uint)
+	for _, result := range results {
+		accountIndexes[result.Address] = result.AccountIndex
+		totalSucceeded += result.Succeeded
+		totalFailed += result.Failed
+		if result.Err != nil {
+			log.Printf("Account index: %d, Address: %s, Succeeded: %d, Failed: %d, Error: %v\n", result.AccountIndex, result.Address.Hex(), result.Succeeded, result.Failed, result.Err)
+			continue
+		}
+		log.Printf("Account index: %d, Address: %s, Succeeded: %d, Failed: %d\n", result.AccountIndex, result.Address.Hex(), result.Succeeded, result.Failed)
 	}
-	log.Println("Mint finished")
+	log.Printf("Accounts: %d, Succeeded: %d, Failed: %d\n", len(results), totalSucceeded, totalFailed)
+	return accountIndexes, totalSucceeded, totalFailed
 }
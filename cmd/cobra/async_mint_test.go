@@ -0,0 +1,132 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/journal"
+	"cronos-tools/src/output"
+	"cronos-tools/src/txengine"
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestMintRun 返回发送到client的mintRun，tracker不在后台查询，结果输出被丢弃
+func newTestMintRun(t *testing.T, client *fakeClient, perAddressMinted uint) *mintRun {
+	jobJournal, err := journal.Create(t.TempDir(), "mint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { jobJournal.Close() })
+	return &mintRun{
+		payload:          []byte(`data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"1000"}`),
+		perAddressMinted: perAddressMinted,
+		tracker:          txengine.NewTracker(client),
+		client:           client,
+		journal:          jobJournal,
+		stream:           &txStream{out: output.NewWriter(io.Discard, output.FormatJSON), indexes: make(map[common.Address]uint)},
+		progress:         map[uint]*journal.AccountProgress{},
+	}
+}
+
+// testAddress 返回testMnemonic第accountIndex个地址
+func testAddress(t *testing.T, keys wallet.KeySource, accountIndex uint) common.Address {
+	key, err := keys.PrivateKey(accountIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return utils.GetAddressFromPrivateKey(key)
+}
+
+func TestRunMintWorkers(t *testing.T) {
+	keys, err := wallet.NewMnemonicSource(testMnemonic, "", utils.DefaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 账户2余额不足跳过，账户3的交易被拒绝，账户4的worker panic
+	client := &fakeClient{sendErrTo: map[common.Address]error{
+		testAddress(t, keys, 2): errors.New("insufficient funds for gas * price + value"),
+		testAddress(t, keys, 3): errors.New("execution reverted"),
+	}}
+	run := newTestMintRun(t, client, 2)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var results []*accountMintResult
+	captureLog(func() {
+		results = runMintWorkers(context.Background(), 0, 5, 2, func(accountIndex uint, result *accountMintResult) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}()
+			time.Sleep(10 * time.Millisecond)
+			if accountIndex == 4 {
+				panic("worker failed")
+			}
+			key, err := keys.PrivateKey(accountIndex)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sender := txengine.NewSender(client, big.NewInt(25), key)
+			result.Address = sender.Address()
+			result.Succeeded, result.Failed, result.Err = run.mintAccount(context.Background(), sender, accountIndex)
+		})
+	})
+	if maxRunning != 2 {
+		t.Errorf("at most %d workers ran at the same time, want 2", maxRunning)
+	}
+	if len(results) != 6 {
+		t.Fatalf("got %d results, want 6", len(results))
+	}
+	for _, result := range results {
+		wantSucceeded, wantFailed, wantErr := uint(2), uint(0), false
+		switch result.AccountIndex {
+		case 2:
+			wantSucceeded, wantFailed = 0, 1
+		case 3:
+			wantSucceeded, wantFailed, wantErr = 0, 1, true
+		case 4:
+			wantSucceeded, wantErr = 0, true
+		}
+		if result.Succeeded != wantSucceeded || result.Failed != wantFailed || (result.Err != nil) != wantErr {
+			t.Errorf("account %d = %d succeeded, %d failed, %v, want %d, %d, error %v", result.AccountIndex, result.Succeeded, result.Failed, result.Err, wantSucceeded, wantFailed, wantErr)
+		}
+	}
+
+	var succeeded, failed uint
+	captureLog(func() { _, succeeded, failed = logMintResults(results) })
+	if succeeded != 6 || failed != 2 {
+		t.Errorf("summary = %d succeeded, %d failed, want 6 and 2", succeeded, failed)
+	}
+	if run.tracker.Pending() != 6 {
+		t.Errorf("tracking %d transactions, want 6", run.tracker.Pending())
+	}
+}
+
+func TestRunMintWorkersStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started []uint
+	results := runMintWorkers(ctx, 0, 9, 1, func(accountIndex uint, result *accountMintResult) {
+		started = append(started, accountIndex)
+		if accountIndex == 2 {
+			// 达到mint进度上限
+			cancel()
+		}
+	})
+	if len(results) != 3 || len(started) != 3 {
+		t.Errorf("started %v, want no account after the context is cancelled", started)
+	}
+}
@@ -1,28 +1,29 @@
 package cobra
 
 import (
+	"context"
+	"cronos-tools/src/indexer"
 	"cronos-tools/src/utils"
-	"encoding/json"
+	"cronos-tools/src/wallet"
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/cobra"
-	"io"
 	"log"
-	"net/http"
+	"math/big"
+	"sort"
+	"sync"
 )
 
 var balanceCmd = &cobra.Command{
 	Use:   "balance",
 	Short: "Get tick balance of an address",
-
-	Run: func(cmd *cobra.Command, args []string) {
-		mnemonic, err := cmd.Flags().GetString("mnemonic")
+	// 部分地址查询失败时只输出错误并以非0状态退出
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := getKeySource(cmd)
 		if err != nil {
-			log.Panicln(errors.New("mnemonic is required"))
-		}
-		if mnemonic == "" {
-			log.Panicln(errors.New("mnemonic is required"))
+			log.Panicln(err)
 		}
 
 		startIndex, err := cmd.Flags().GetUint("start-index")
@@ -38,97 +39,176 @@ var balanceCmd = &cobra.Command{
 			log.Panicln(errors.New("start-index must less than or equal to end-index"))
 		}
 
-		forAllTicks := false
 		tick, err := cmd.Flags().GetString("tick")
 		if err != nil {
 			log.Panicln(errors.New("tick is required"))
 		}
-		if tick == "" {
-			forAllTicks = true
+		concurrency, err := cmd.Flags().GetUint("concurrency")
+		if err != nil {
+			log.Panicln(errors.New("concurrency is required"))
+		}
+		if concurrency == 0 {
+			log.Panicln(errors.New("concurrency must bigger than 0"))
+		}
+		// 指定rpc时同时查询CRO余额
+		rpc, err := cmd.Flags().GetString("rpc")
+		if err != nil {
+			log.Panicln(err)
+		}
+		var client balanceReader
+		if rpc != "" {
+			pool, err := dialRPC(cmd, rpc)
+			if err != nil {
+				log.Panicln(err)
+			}
+			client = pool
+		}
+		idx, err := newIndexer(cmd)
+		if err != nil {
+			log.Panicln(err)
 		}
-		totalInscriptions := make(map[string]int)
 
+		// 多个worker同时查询，indexer负责限速
+		accounts := make([]*accountBalance, endIndex-startIndex+1)
+		jobs := make(chan *accountBalance)
+		var wg sync.WaitGroup
+		for w := uint(0); w < concurrency; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for account := range jobs {
+					account.fetch(context.Background(), keys, idx, client)
+				}
+			}()
+		}
 		for i := startIndex; i <= endIndex; i++ {
-			// 获取当前账户的私钥
-			accountPrivateKey := utils.GetPrivateKey(mnemonic, i)
-			// 获取当前账户的地址
-			accountAddress := utils.GetAddressFromPrivateKey(accountPrivateKey)
-			// 获取当前账户的余额
-			ticksBalance, err := GetInscriptionBalance(accountAddress)
-			if err != nil {
-				log.Panicln("Error fetching inscription balance:", err)
+			accounts[i-startIndex] = &accountBalance{index: i}
+			jobs <- accounts[i-startIndex]
+		}
+		close(jobs)
+		wg.Wait()
+
+		out := getOutput(cmd)
+		totalInscriptions := make(map[string]int)
+		totalCRO := new(big.Int)
+		var failed []*accountBalance
+		for _, account := range accounts {
+			if account.err != nil {
+				failed = append(failed, account)
+				continue
 			}
-			if len(ticksBalance.Data) == 0 {
-				log.Println("Account index:", i, "Address:", accountAddress.Hex(), "No balance")
+			rows := account.rows(tick)
+			if len(rows) == 0 {
+				log.Println("Account index:", account.index, "Address:", account.address.Hex(), "No balance")
 				continue
 			}
-			if forAllTicks {
-				for _, balance := range ticksBalance.Data {
-					totalInscriptions[balance.Tick] += balance.Amount
-					log.Printf("Account index: %d, Address: %s, Tick: %s, Amount: %d\n", i, accountAddress.Hex(), balance.Tick, balance.Amount)
-				}
-			} else {
-				hasBalance := false
-				for _, balance := range ticksBalance.Data {
-					if balance.Tick == tick {
-						totalInscriptions[balance.Tick] += balance.Amount
-						hasBalance = true
-						log.Printf("Account index: %d, Address: %s, Tick: %s, Amount: %d\n", i, accountAddress.Hex(), balance.Tick, balance.Amount)
-					}
-				}
-				if !hasBalance {
-					log.Printf("Account index: %d, Address: %s, Tick: %s, Amount: %d\n", i, accountAddress.Hex(), tick, 0)
-				}
+			for _, row := range rows {
+				totalInscriptions[row.Tick] += row.Amount
+				writeOutput(out, row)
 			}
+			if account.native != nil {
+				totalCRO.Add(totalCRO, account.native)
+			}
+		}
+
+		ticks := make([]string, 0, len(totalInscriptions))
+		for t := range totalInscriptions {
+			ticks = append(ticks, t)
 		}
-		log.Println("totalInscriptions:", totalInscriptions)
+		sort.Strings(ticks)
+		if err := out.Flush(); err != nil {
+			log.Panicln(err)
+		}
+		// stdout只输出balanceRow，合计和失败的地址写到日志，csv和json的每一行格式相同
+		for _, t := range ticks {
+			log.Println("Tick:", t, "Total inscriptions:", totalInscriptions[t])
+		}
+		for _, account := range failed {
+			log.Println("Account index:", account.index, "Address:", account.address.Hex(), "Failed:", account.err)
+		}
+		if client != nil {
+			log.Println("Total CRO:", formatCRO(totalCRO))
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("can not fetch balance of %d of %d addresses", len(failed), len(accounts))
+		}
+		return nil
 	},
 }
 
-func init() {
-	rootCmd.AddCommand(balanceCmd)
-	balanceCmd.Flags().StringP("mnemonic", "m", "", "Set mnemonic")
-	balanceCmd.Flags().StringP("tick", "t", "", "Specify the tick")
-	balanceCmd.Flags().UintP("start-index", "s", 0, "Start index of bip-44 sequence addresses,default 0")
-	balanceCmd.Flags().UintP("end-index", "e", 0, "End index of bip-44 sequence addresses,default 0")
+// accountBalance 一个账户的查询结果
+type accountBalance struct {
+	index    uint
+	address  common.Address
+	balances []indexer.Balance
+	native   *big.Int
+	err      error
 }
 
-// Get all ticks balance of an address
-// https://api.croscribe.com/balance/0xeb0c56a29e13F1594d794158c507f77bfd5B6eC8
+// balanceReader 查询地址的CRO余额
+type balanceReader interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
 
-func GetInscriptionBalance(address common.Address) (*TicksBalance, error) {
-	baseUrl := "https://api.croscribe.com/balance"
-	url := fmt.Sprintf("%s/%s", baseUrl, address.Hex())
-	resp, err := http.Get(url)
+// fetch 查询铭文余额，client不为空时同时查询CRO余额，错误记录在err中
+func (a *accountBalance) fetch(ctx context.Context, keys wallet.KeySource, idx indexer.Indexer, client balanceReader) {
+	// 获取当前账户的私钥
+	accountPrivateKey, err := keys.PrivateKey(a.index)
 	if err != nil {
-		fmt.Println("Error fetching data:", err)
-		return nil, err
+		a.err = err
+		return
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Error reading response body:", err)
-		return nil, err
+	// 获取当前账户的地址
+	a.address = utils.GetAddressFromPrivateKey(accountPrivateKey)
+	// 获取当前账户的铭文余额，indexer会限速并重试临时错误
+	if a.balances, a.err = idx.Balances(ctx, a.address); a.err != nil {
+		log.Println("Account index:", a.index, "Address:", a.address.Hex(), "Error fetching inscription balance:", a.err)
+		return
 	}
+	if client == nil {
+		return
+	}
+	// rpc调用由节点池按--retry-*重试
+	if a.native, a.err = client.BalanceAt(ctx, a.address, nil); a.err != nil {
+		log.Println("Account index:", a.index, "Address:", a.address.Hex(), "Error fetching CRO balance:", a.err)
+	}
+}
 
-	var ticksBalance TicksBalance
-	if err := json.Unmarshal(body, &ticksBalance); err != nil {
-		fmt.Println("Error parsing JSON:", err)
-		return nil, err
-
+// rows 返回要输出的行，tick不为空时只输出该tick。查询了CRO余额时每行都带CRO列，
+// 没有铭文的账户也输出一行；既没有铭文也没有CRO余额时返回空
+func (a *accountBalance) rows(tick string) []balanceRow {
+	cro := ""
+	if a.native != nil {
+		cro = formatCRO(a.native)
+	}
+	var rows []balanceRow
+	for _, balance := range a.balances {
+		if tick != "" && balance.Tick != tick {
+			continue
+		}
+		rows = append(rows, balanceRow{AccountIndex: a.index, Address: a.address, Tick: balance.Tick, Amount: balance.Amount, CRO: cro})
 	}
-	return &ticksBalance, nil
+	if len(rows) > 0 || (tick == "" && a.native == nil) {
+		return rows
+	}
+	return []balanceRow{{AccountIndex: a.index, Address: a.address, Tick: tick, CRO: cro}}
 }
 
-type TicksBalance struct {
-	Data []TickBalanceInfo `json:"balances"`
+// balanceRow 一个账户持有的一种铭文，CRO是账户的原生代币余额
+type balanceRow struct {
+	AccountIndex uint           `json:"account_index"`
+	Address      common.Address `json:"address"`
+	Tick         string         `json:"tick"`
+	Amount       int            `json:"amount"`
+	CRO          string         `json:"cro,omitempty"`
 }
 
-type TickBalanceInfo struct {
-	TokenId  int    `json:"token_id"`
-	Chain    string `json:"chain"`
-	Protocol string `json:"protocol"`
-	Tick     string `json:"tick"`
-	Amount   int    `json:"amount"`
+func init() {
+	rootCmd.AddCommand(balanceCmd)
+	addKeyFlags(balanceCmd)
+	balanceCmd.Flags().StringP("tick", "t", "", "Specify the tick")
+	balanceCmd.Flags().StringP("rpc", "r", "", "Also fetch the CRO balance of each address from this rpc url, several urls separated by commas")
+	balanceCmd.Flags().UintP("start-index", "s", 0, "Start index of bip-44 sequence addresses,default 0")
+	balanceCmd.Flags().UintP("end-index", "e", 0, "End index of bip-44 sequence addresses,default 0")
+	balanceCmd.Flags().UintP("concurrency", "", 10, "Number of addresses fetched at the same time,default 10")
 }
@@ -0,0 +1,133 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/indexer"
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// failingIndexer 查询余额时返回err，其他查询交给内嵌的索引服务
+type failingIndexer struct {
+	indexer.Indexer
+	err error
+}
+
+func (f *failingIndexer) Balances(ctx context.Context, address common.Address) ([]indexer.Balance, error) {
+	return nil, f.err
+}
+
+func TestAccountBalanceFetch(t *testing.T) {
+	keys, err := wallet.NewMnemonicSource(testMnemonic, "", utils.DefaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	idx := indexer.NewMemory()
+	idx.SetBalance(address, "cros", 1000)
+
+	account := &accountBalance{index: 0}
+	account.fetch(context.Background(), keys, idx, nil)
+	if account.err != nil {
+		t.Fatal(account.err)
+	}
+	if account.address != address {
+		t.Errorf("address = %s, want %s", account.address.Hex(), address.Hex())
+	}
+	if len(account.balances) != 1 || account.balances[0].Tick != "cros" || account.balances[0].Amount != 1000 {
+		t.Errorf("balances = %+v, want 1000 cros", account.balances)
+	}
+	if account.native != nil {
+		t.Errorf("native = %s without an rpc client, want nil", account.native)
+	}
+
+	// 其他账户没有余额
+	empty := &accountBalance{index: 1}
+	empty.fetch(context.Background(), keys, idx, nil)
+	if empty.err != nil || len(empty.balances) != 0 {
+		t.Errorf("fetch of an empty account = %+v %v, want no balances", empty.balances, empty.err)
+	}
+
+	fetchErr := errors.New("indexer is down")
+	failed := &accountBalance{index: 0}
+	failed.fetch(context.Background(), keys, &failingIndexer{Indexer: indexer.NewMemory(), err: fetchErr}, nil)
+	if !errors.Is(failed.err, fetchErr) || failed.address != address {
+		t.Errorf("fetch with a failing indexer = %s %v, want %s and the indexer error", failed.address.Hex(), failed.err, address.Hex())
+	}
+}
+
+func TestAccountBalanceCRO(t *testing.T) {
+	keys, err := wallet.NewMnemonicSource(testMnemonic, "", utils.DefaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	idx := indexer.NewMemory()
+	idx.SetBalance(address, "cros", 1000)
+	idx.SetBalance(address, "moon", 5)
+	client := &fakeClient{balance: new(big.Int).Mul(big.NewInt(15), big.NewInt(1e17))}
+
+	account := &accountBalance{index: 0}
+	account.fetch(context.Background(), keys, idx, client)
+	if account.err != nil {
+		t.Fatal(account.err)
+	}
+	if account.native == nil || account.native.Cmp(client.balance) != 0 {
+		t.Fatalf("native = %v, want %s", account.native, client.balance)
+	}
+
+	tests := []struct {
+		name    string
+		account *accountBalance
+		tick    string
+		want    []balanceRow
+	}{
+		{"every tick", account, "", []balanceRow{
+			{Address: address, Tick: "cros", Amount: 1000, CRO: "1.5"},
+			{Address: address, Tick: "moon", Amount: 5, CRO: "1.5"},
+		}},
+		{"one tick", account, "moon", []balanceRow{
+			{Address: address, Tick: "moon", Amount: 5, CRO: "1.5"},
+		}},
+		// 没有该tick时仍输出CRO余额
+		{"missing tick", account, "bull", []balanceRow{
+			{Address: address, Tick: "bull", CRO: "1.5"},
+		}},
+		{"cro only", &accountBalance{address: address, native: big.NewInt(0)}, "", []balanceRow{
+			{Address: address, CRO: "0"},
+		}},
+		{"missing tick without rpc", &accountBalance{address: address}, "bull", []balanceRow{
+			{Address: address, Tick: "bull"},
+		}},
+		{"no balance", &accountBalance{address: address}, "", nil},
+	}
+	for _, test := range tests {
+		if rows := test.account.rows(test.tick); !reflect.DeepEqual(rows, test.want) {
+			t.Errorf("%s: rows = %+v, want %+v", test.name, rows, test.want)
+		}
+	}
+
+	// CRO余额查询失败时记录错误
+	rpcErr := errors.New("rpc is down")
+	failed := &accountBalance{index: 0}
+	failed.fetch(context.Background(), keys, idx, &failingBalanceReader{err: rpcErr})
+	if !errors.Is(failed.err, rpcErr) || failed.native != nil {
+		t.Errorf("fetch with a failing rpc = %v %v, want the rpc error", failed.native, failed.err)
+	}
+}
+
+// failingBalanceReader 查询CRO余额时返回err
+type failingBalanceReader struct {
+	err error
+}
+
+func (f *failingBalanceReader) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return nil, f.err
+}
@@ -2,30 +2,36 @@ package cobra
 
 import (
 	"context"
-	"cronos-tools/src/utils"
+	"cronos-tools/src/indexer"
+	"cronos-tools/src/inscription"
+	"cronos-tools/src/journal"
+	"cronos-tools/src/rpcerr"
+	"cronos-tools/src/txengine"
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
 	"log"
 	"strconv"
 	"strings"
-	"time"
 )
 
 var collectCmd = &cobra.Command{
 	Use:   "collect",
 	Short: "Collect all inscriptions about one tick",
 	Run: func(cmd *cobra.Command, args []string) {
-		mnemonic, err := cmd.Flags().GetString("mnemonic")
+		jobJournal, progress, err := openJournal(cmd)
 		if err != nil {
-			log.Panicln(errors.New("mnemonic is required"))
+			log.Panicln(err)
+		}
+		defer jobJournal.Close()
+		keys, err := getKeySource(cmd)
+		if err != nil {
+			log.Panicln(err)
 		}
-		if mnemonic == "" {
-			log.Panicln(errors.New("mnemonic is required"))
+		if err := checkResumedAddresses(keys, progress); err != nil {
+			log.Panicln(err)
 		}
 
 		startIndex, err := cmd.Flags().GetUint("start-index")
@@ -51,6 +57,9 @@ var collectCmd = &cobra.Command{
 			log.Panicln(errors.New("tick is required"))
 		}
 		tick = strings.TrimSpace(tick)
+		if err := inscription.ValidateTick(tick); err != nil {
+			log.Panicln(err)
+		}
 
 		rpc, err := cmd.Flags().GetString("rpc")
 		if err != nil {
@@ -71,145 +80,155 @@ var collectCmd = &cobra.Command{
 		collector = strings.TrimPrefix(collector, "0x")
 		collectorAddress := common.HexToAddress(collector)
 
-		client, err := ethclient.Dial(rpc)
+		client, err := dialRPC(cmd, rpc)
+		if err != nil {
+			log.Panicln(err)
+		}
+		feeConfig, err := getFeeConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		gasConfig, err := getGasConfig(cmd)
 		if err != nil {
 			log.Panicln(err)
 		}
-		gasLimit := uint64(22100)
 
 		networkID, err := client.NetworkID(context.Background())
 		if err != nil {
 			log.Panicln(err)
 		}
 
+		idx, err := newIndexer(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		stream := newTxStream(cmd)
+		tracker := newTracker(context.Background(), client, jobJournal, stream)
+		run := &collectRun{idx: idx, tick: tick, collector: collectorAddress, tracker: tracker, journal: jobJournal, stream: stream}
+		accountIndexes := make(map[common.Address]uint)
 		for i := startIndex; i <= endIndex; i++ {
 			// 获取当前账户的私钥
-			accountPrivateKey := utils.GetPrivateKey(mnemonic, i)
-			// 获取当前账户的地址
-			accountAddress := utils.GetAddressFromPrivateKey(accountPrivateKey)
-			if accountAddress == collectorAddress {
-				continue
-			}
-			// 获取当前账户的所有铭文余额
-			allTicksBalance, err := GetInscriptionBalance(accountAddress)
+			accountPrivateKey, err := keys.PrivateKey(i)
 			if err != nil {
-				log.Panicln("Error fetching inscription balance:", err)
+				log.Panicln(err)
 			}
-			if len(allTicksBalance.Data) == 0 {
-				log.Println("Account index:", i, "Address:", accountAddress.Hex(), "No balance")
+			sender := txengine.NewSender(client, networkID, accountPrivateKey)
+			sender.Retry = client.Retry
+			sender.Gas = gasConfig
+			sender.Fees = feeConfig
+			// 获取当前账户的地址
+			accountAddress := sender.Address()
+			if accountAddress == collectorAddress {
 				continue
 			}
-			// 获取当前账户的指定铭文余额
-			var tickBalance *TickBalanceInfo
-			for _, tb := range allTicksBalance.Data {
-				if strings.ToLower(tb.Tick) == strings.ToLower(tick) {
-					tickBalance = &tb
-					log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Tick:", tick, "Amount:", tb.Amount)
-					break
+			stream.account(i, accountAddress)
+			// 发送前记录已签名的交易，进程在发送时中断也不会在恢复时重复collect
+			accountIndex := i
+			sender.OnSigned = func(tx *types.Transaction) {
+				if err := jobJournal.Signed(accountIndex, accountAddress, tx); err != nil {
+					log.Println("Can not write journal", err)
 				}
 			}
-			if tickBalance == nil {
-				log.Println("Account index:", i, "Address:", accountAddress.Hex(), "No balance for tick", tick)
-				continue
+			p, ok := progress[i]
+			if ok {
+				recoverUnsent(context.Background(), client, jobJournal, p)
 			}
-
-			// 获取当前账户的gasPrice
-			gasPrice, err := client.SuggestGasPrice(context.Background())
-			if err != nil {
-				log.Println("Can not get gas price ", err)
-				// 如果获取gasPrice失败，则等待10秒后
-				for x := 0; x < 5; x++ {
-					time.Sleep(5 * time.Second)
-					gasPrice, err = client.SuggestGasPrice(context.Background())
-					if err == nil {
-						continue
-					}
-				}
-				if err != nil {
-					log.Panicln("Can not get gas price after retry 5 times ", err)
+			if ok && (p.Confirmed > 0 || len(p.Pending) > 0) {
+				// 上次任务已经发送过，只继续跟踪
+				for _, tx := range p.Pending {
+					tracker.Track(accountAddress, tx, journaledBump(jobJournal, sender, accountIndex))
 				}
-			}
-
-			// 检查当前账户的native coin余额是否足够支付gas fee
-			nativeCoinBalance, err := client.BalanceAt(context.Background(), accountAddress, nil)
-			if err != nil {
-				log.Println("Can not get native coin balance ", err)
-				// 如果获取balance失败，则等待10秒后
-				for y := 0; y < 5; y++ {
-					time.Sleep(5 * time.Second)
-					nativeCoinBalance, err = client.BalanceAt(context.Background(), accountAddress, nil)
-					if err == nil {
-						continue
-					}
-				}
-				if err != nil {
-					log.Panicln("Can not get native coin balance after retry 5 times ", err)
-				}
-			}
-
-			// 计算gas fee
-			gasFee := decimal.NewFromBigInt(gasPrice, 0).Mul(decimal.NewFromInt(int64(gasLimit))).BigInt()
-			if nativeCoinBalance.Cmp(gasFee) < 0 {
-				log.Println("Account " + accountAddress.Hex() + " native coin balance is not enough to pay for gas fee")
-				log.Println("Switch to next account")
+				accountIndexes[accountAddress] = i
+				log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Already collected in journal, skip")
 				continue
 			}
-
-			// 获取当前账户的nonce
-			nonce, err := client.PendingNonceAt(context.Background(), accountAddress)
+			sent, err := run.collectAccount(context.Background(), sender, i)
 			if err != nil {
-				log.Println("Can not get nonce ", err)
-				// 如果获取nonce失败，则等待10秒后
-				for z := 0; z < 5; z++ {
-					time.Sleep(5 * time.Second)
-					nonce, err = client.PendingNonceAt(context.Background(), accountAddress)
-					if err == nil {
-						continue
-					}
+				if rpcerr.PolicyOf(err) == rpcerr.PolicySkipAccount {
+					log.Println("Account " + accountAddress.Hex() + " native coin balance is not enough to pay for gas fee")
+					log.Println("Switch to next account")
+					continue
 				}
-				if err != nil {
-					log.Panicln("Can not get nonce after retry 5 times ", err)
-				}
-			}
-			// 构建payload
-			payloadString := fmt.Sprintf(`data:,{"p":"crc-20","op":"transfer","tick":"%s","amt":"%s"}`, tick, strconv.Itoa(tickBalance.Amount))
-			payload := []byte(payloadString)
-			log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Tick:", tick, "Amount:", tickBalance.Amount, "To:", collectorAddress.Hex())
-			log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Payload:", string(payload), "To:", collectorAddress.Hex())
-			// 构造交易
-			tx := types.NewTx(&types.LegacyTx{
-				Nonce:    nonce,
-				To:       &collectorAddress,
-				Value:    decimal.Zero.BigInt(),
-				Gas:      gasLimit,
-				GasPrice: gasPrice,
-				Data:     payload,
-			})
-			log.Println("Account index:", i, "Address:", accountAddress.Hex(), "build tx:", tx.Hash().Hex())
-
-			signedTx, err := types.SignTx(tx, types.NewEIP155Signer(networkID), accountPrivateKey)
-			if err != nil {
-				log.Panicln("Can not sign transaction ", err)
-			}
-			// 发送交易
-			err = client.SendTransaction(context.Background(), signedTx)
-			if err != nil {
 				log.Println("Account " + accountAddress.Hex() + " send transaction failed")
 				log.Panicln("Can not send transaction ", err)
 			}
-			txHash := signedTx.Hash()
-			txHashString := txHash.Hex()
-			log.Println("Account index: ", i, " Address: ", accountAddress.Hex(), " Tx hash: ", txHashString, " Payload: ", string(payload))
+			if sent {
+				accountIndexes[accountAddress] = i
+			}
 		}
+		waitConfirmations(cmd, tracker, accountIndexes)
+		stream.flush()
 	},
 }
 
+// collectRun 一次collect的参数和共享状态
+type collectRun struct {
+	idx       indexer.Indexer
+	tick      string
+	collector common.Address
+	tracker   *txengine.Tracker
+	journal   *journal.Journal
+	stream    *txStream
+}
+
+// collectAccount transfers the whole tick balance of the sender's account to the collector and
+// hands the transaction to the tracker. It returns whether a transaction was sent; errors of the
+// node are returned so the caller can skip accounts that can not pay for gas.
+func (r *collectRun) collectAccount(ctx context.Context, sender *txengine.Sender, accountIndex uint) (bool, error) {
+	accountAddress := sender.Address()
+	// 获取当前账户的所有铭文余额
+	allTicksBalance, err := r.idx.Balances(ctx, accountAddress)
+	if err != nil {
+		return false, fmt.Errorf("can not fetch inscription balance: %w", err)
+	}
+	// 获取当前账户的指定铭文余额
+	var tickBalance *indexer.Balance
+	for _, tb := range allTicksBalance {
+		if strings.EqualFold(tb.Tick, r.tick) {
+			tickBalance = &tb
+			break
+		}
+	}
+	if tickBalance == nil {
+		log.Println("Account index:", accountIndex, "Address:", accountAddress.Hex(), "No balance for tick", r.tick)
+		return false, nil
+	}
+
+	// 构建payload
+	payload, err := inscription.Transfer{Tick: r.tick, Amt: strconv.Itoa(tickBalance.Amount)}.Encode()
+	if err != nil {
+		return false, err
+	}
+	log.Println("Account index:", accountIndex, "Address:", accountAddress.Hex(), "Tick:", r.tick, "Amount:", tickBalance.Amount, "To:", r.collector.Hex())
+	log.Println("Account index:", accountIndex, "Address:", accountAddress.Hex(), "Payload:", string(payload), "To:", r.collector.Hex())
+	if err := r.journal.Planned(accountIndex, accountAddress, 1); err != nil {
+		log.Println("Can not write journal", err)
+	}
+	// 发送交易
+	result, err := sender.Send(ctx, r.collector, payload)
+	if err != nil {
+		r.stream.failed(accountIndex, accountAddress, err)
+		return false, err
+	}
+	r.stream.sent(accountIndex, accountAddress, result)
+	log.Println("Account index: ", accountIndex, " Address: ", accountAddress.Hex(), " Tx hash: ", result.Hash.Hex(), " Payload: ", string(payload))
+	if err := r.journal.Sent(accountIndex, accountAddress, result.Tx); err != nil {
+		log.Println("Can not write journal", err)
+	}
+	r.tracker.Track(accountAddress, result.Tx, journaledBump(r.journal, sender, accountIndex))
+	return true, nil
+}
+
 func init() {
 	rootCmd.AddCommand(collectCmd)
-	collectCmd.Flags().StringP("mnemonic", "m", "", "Set mnemonic")
+	addKeyFlags(collectCmd)
 	collectCmd.Flags().StringP("tick", "t", "", "Specify the tick")
-	collectCmd.Flags().StringP("rpc", "r", "", "Specify the rpc url")
+	collectCmd.Flags().StringP("rpc", "r", "", "Specify the rpc url, several urls separated by commas fail over to each other")
 	collectCmd.Flags().StringP("collector", "c", "", "Specify the collector address")
 	collectCmd.Flags().UintP("start-index", "s", 0, "Start index of bip-44 sequence addresses,default 0")
 	collectCmd.Flags().UintP("end-index", "e", 0, "End index of bip-44 sequence addresses,default 0")
+	addFeeFlags(collectCmd)
+	addGasFlags(collectCmd)
+	addConfirmFlags(collectCmd)
+	addJournalFlags(collectCmd)
 }
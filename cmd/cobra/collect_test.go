@@ -0,0 +1,84 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/indexer"
+	"cronos-tools/src/journal"
+	"cronos-tools/src/output"
+	"cronos-tools/src/rpcerr"
+	"cronos-tools/src/txengine"
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"math/big"
+	"testing"
+)
+
+func TestCollectAccount(t *testing.T) {
+	keys, err := wallet.NewMnemonicSource(testMnemonic, "", utils.DefaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	idx := indexer.NewMemory()
+	for i := uint(0); i < 3; i++ {
+		idx.SetBalance(testAddress(t, keys, i), "CROS", 1000)
+	}
+	tests := []struct {
+		name       string
+		tick       string
+		sendErr    error
+		wantSent   bool
+		wantPolicy rpcerr.Policy
+	}{
+		{name: "sent", tick: "cros", wantSent: true},
+		{name: "no balance of tick", tick: "moon"},
+		{name: "skip account", tick: "cros", sendErr: errors.New("insufficient funds for gas * price + value"), wantPolicy: rpcerr.PolicySkipAccount},
+		{name: "node error", tick: "cros", sendErr: errors.New("execution reverted"), wantPolicy: rpcerr.PolicyFail},
+	}
+	for _, test := range tests {
+		client := &fakeClient{}
+		if test.sendErr != nil {
+			client.sendErrTo = map[common.Address]error{collector: test.sendErr}
+		}
+		jobJournal, err := journal.Create(t.TempDir(), "collect", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		run := &collectRun{
+			idx:       idx,
+			tick:      test.tick,
+			collector: collector,
+			tracker:   txengine.NewTracker(client),
+			journal:   jobJournal,
+			stream:    &txStream{out: output.NewWriter(io.Discard, output.FormatJSON), indexes: make(map[common.Address]uint)},
+		}
+		key, err := keys.PrivateKey(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sent bool
+		captureLog(func() {
+			sent, err = run.collectAccount(context.Background(), txengine.NewSender(client, big.NewInt(25), key), 1)
+		})
+		jobJournal.Close()
+		if sent != test.wantSent || run.tracker.Pending() != map[bool]int{true: 1, false: 0}[test.wantSent] {
+			t.Errorf("%s: collectAccount sent %v, tracking %d, want sent %v", test.name, sent, run.tracker.Pending(), test.wantSent)
+		}
+		if test.wantPolicy == "" && err != nil || test.wantPolicy != "" && rpcerr.PolicyOf(err) != test.wantPolicy {
+			t.Errorf("%s: collectAccount error = %v, want policy %q", test.name, err, test.wantPolicy)
+		}
+	}
+
+	// 索引服务失败时返回错误
+	run := &collectRun{idx: &failingIndexer{Indexer: idx, err: errors.New("indexer is down")}, tick: "cros", collector: collector}
+	key, err := keys.PrivateKey(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run.collectAccount(context.Background(), txengine.NewSender(&fakeClient{}, big.NewInt(25), key), 0); err == nil {
+		t.Error("collectAccount with a failing indexer returned no error")
+	}
+}
@@ -0,0 +1,82 @@
+package cobra
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// configAnnotation 标记值来自配置文件的参数，恢复任务时任务日志中保存的值优先于配置文件
+const configAnnotation = "cronos-tools/config"
+
+// defaultConfigPath 默认配置文件路径，文件不存在时忽略
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".cronos-tools", "config.json")
+	}
+	return filepath.Join(home, ".cronos-tools", "config.json")
+}
+
+// applyConfig 读取--config指定的json文件，文件中的键是参数名，为命令行没有指定的参数设置值，
+// 例如{"retry-max-attempts": 8, "retry-call-timeout": "30s"}。当前命令没有的参数会被忽略。
+// 设置的参数用configAnnotation标记，优先级为命令行、任务日志、配置文件
+func applyConfig(cmd *cobra.Command) error {
+	path, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !cmd.Flags().Changed("config") {
+			return nil
+		}
+		return err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("can not parse config %s: %w", path, err)
+	}
+	for name, value := range values {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			text = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("config %s: value of %s must be a string, number or bool", path, name)
+		}
+		if err := cmd.Flags().Set(name, text); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+		if err := cmd.Flags().SetAnnotation(name, configAnnotation, []string{path}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fromConfig 判断参数的值是否来自配置文件
+func fromConfig(flag *pflag.Flag) bool {
+	_, ok := flag.Annotations[configAnnotation]
+	return ok
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringP("config", "", defaultConfigPath(), "Json file of default flag values such as {\"retry-max-attempts\": 8}, flags on the command line take precedence")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return applyConfig(cmd)
+	}
+}
@@ -0,0 +1,66 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/journal"
+	"cronos-tools/src/txengine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"log"
+	"sort"
+	"time"
+)
+
+// addConfirmFlags 添加等待交易上链相关的参数
+func addConfirmFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationP("confirm-timeout", "", 10*time.Minute, "Max time to wait for sent transactions to be included after sending,default 10m")
+}
+
+// newTracker 创建交易回执跟踪器，并在后台开始查询。jobJournal和stream不为空时记录每笔交易的最终结果
+func newTracker(ctx context.Context, client txengine.ReceiptClient, jobJournal *journal.Journal, stream *txStream) *txengine.Tracker {
+	tracker := txengine.NewTracker(client)
+	tracker.OnConfirm = func(confirmation *txengine.Confirmation) {
+		if jobJournal != nil {
+			if err := jobJournal.Finished(confirmation); err != nil {
+				log.Println("Can not write journal", err)
+			}
+		}
+		if stream != nil {
+			stream.confirmed(confirmation)
+		}
+		log.Println("Address:", confirmation.Account.Hex(), "Nonce:", confirmation.Nonce, "Tx hash:", confirmation.Hash.Hex(), "Status:", confirmation.Status, "Block:", confirmation.BlockNumber, "Gas used:", confirmation.GasUsed)
+	}
+	tracker.Start(ctx)
+	return tracker
+}
+
+// waitConfirmations 等待所有交易有最终结果，并按账户打印上链数量
+func waitConfirmations(cmd *cobra.Command, tracker *txengine.Tracker, accountIndexes map[common.Address]uint) {
+	timeout, err := cmd.Flags().GetDuration("confirm-timeout")
+	if err != nil {
+		log.Panicln(err)
+	}
+	log.Println("Waiting for", tracker.Pending(), "transactions to be included")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	tracker.Wait(ctx)
+	if pending := tracker.Pending(); pending > 0 {
+		log.Println(pending, "transactions are still pending after", timeout)
+	}
+
+	summary := tracker.Summary()
+	addresses := make([]common.Address, 0, len(summary))
+	for address := range summary {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return accountIndexes[addresses[i]] < accountIndexes[addresses[j]]
+	})
+	totalConfirmed := 0
+	for _, address := range addresses {
+		counts := summary[address]
+		totalConfirmed += counts.Confirmed
+		log.Printf("Account index: %d, Address: %s, Confirmed: %d, Reverted: %d, Replaced: %d, Dropped: %d\n", accountIndexes[address], address.Hex(), counts.Confirmed, counts.Reverted, counts.Replaced, counts.Dropped)
+	}
+	log.Println("Total confirmed:", totalConfirmed)
+}
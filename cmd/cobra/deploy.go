@@ -0,0 +1,135 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/indexer"
+	"cronos-tools/src/inscription"
+	"cronos-tools/src/txengine"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"log"
+	"strings"
+)
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy a new crc-20 tick",
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := getKeySource(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		rpc, err := cmd.Flags().GetString("rpc")
+		if err != nil {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		if rpc == "" {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		index, err := cmd.Flags().GetUint("index")
+		if err != nil {
+			log.Panicln(errors.New("index is required"))
+		}
+		tick, err := cmd.Flags().GetString("tick")
+		if err != nil {
+			log.Panicln(errors.New("tick is required"))
+		}
+		maxSupply, err := cmd.Flags().GetString("max")
+		if err != nil {
+			log.Panicln(errors.New("max is required"))
+		}
+		lim, err := cmd.Flags().GetString("lim")
+		if err != nil {
+			log.Panicln(errors.New("lim is required"))
+		}
+		deploy, payload, err := deployPayload(tick, maxSupply, lim)
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		// 检查tick是否已经被部署
+		idx, err := newIndexer(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		if err := checkTickNotDeployed(context.Background(), idx, deploy.Tick); err != nil {
+			log.Panicln(err)
+		}
+
+		client, err := dialRPC(cmd, rpc)
+		if err != nil {
+			log.Panicln(err)
+		}
+		networkID, err := client.NetworkID(context.Background())
+		if err != nil {
+			log.Panicln(err)
+		}
+		feeConfig, err := getFeeConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		gasConfig, err := getGasConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		accountPrivateKey, err := keys.PrivateKey(index)
+		if err != nil {
+			log.Panicln(err)
+		}
+		sender := txengine.NewSender(client, networkID, accountPrivateKey)
+		sender.Retry = client.Retry
+		sender.Gas = gasConfig
+		sender.Fees = feeConfig
+		accountAddress := sender.Address()
+
+		// deploy铭文发送给自己
+		result, err := sender.Send(context.Background(), accountAddress, payload)
+		if err != nil {
+			log.Panicln("Can not send transaction ", err)
+		}
+		log.Println("Account index: ", index, " Address: ", accountAddress.Hex(), " Tx hash: ", result.Hash.Hex(), " Payload: ", string(payload))
+
+		tracker := newTracker(context.Background(), client, nil, nil)
+		tracker.Track(accountAddress, result.Tx, sender.Bump)
+		waitConfirmations(cmd, tracker, map[common.Address]uint{accountAddress: index})
+	},
+}
+
+// deployPayload 去掉参数两端的空白，校验并编码deploy铭文
+func deployPayload(tick string, maxSupply string, lim string) (inscription.Deploy, []byte, error) {
+	deploy := inscription.Deploy{
+		Tick: strings.TrimSpace(tick),
+		Max:  strings.TrimSpace(maxSupply),
+		Lim:  strings.TrimSpace(lim),
+	}
+	payload, err := deploy.Encode()
+	return deploy, payload, err
+}
+
+// checkTickNotDeployed 索引服务中已有同名tick（不区分大小写）时返回错误
+func checkTickNotDeployed(ctx context.Context, idx indexer.Indexer, tick string) error {
+	tickInfo, err := idx.TickInfo(ctx, tick)
+	if errors.Is(err, indexer.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching ticks info: %w", err)
+	}
+	return errors.New("tick " + tickInfo.Tick + " is already deployed")
+}
+
+func init() {
+	rootCmd.AddCommand(deployCmd)
+	addKeyFlags(deployCmd)
+	deployCmd.Flags().StringP("rpc", "r", "", "Specify the rpc url, several urls separated by commas fail over to each other")
+	deployCmd.Flags().UintP("index", "i", 0, "Index of the bip-44 sequence address sending the deploy inscription,default 0")
+	deployCmd.Flags().StringP("tick", "t", "", "Tick to deploy")
+	deployCmd.Flags().StringP("max", "", "", "Max supply of the tick")
+	deployCmd.Flags().StringP("lim", "", "", "Limit of each mint")
+	addFeeFlags(deployCmd)
+	addGasFlags(deployCmd)
+	addConfirmFlags(deployCmd)
+}
@@ -0,0 +1,67 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/indexer"
+	"cronos-tools/src/inscription"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDeployPayload(t *testing.T) {
+	deploy, payload, err := deployPayload(" cros ", "21000000 ", " 1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"21000000","lim":"1000"}`
+	if string(payload) != want {
+		t.Errorf("payload = %s, want %s", payload, want)
+	}
+	if deploy.Tick != "cros" {
+		t.Errorf("tick = %q, want the trimmed tick", deploy.Tick)
+	}
+	// 发送出去的payload能被解析回同一个deploy铭文
+	if parsed, err := inscription.Parse(payload); err != nil || parsed != deploy {
+		t.Errorf("Parse(payload) = %+v %v, want %+v", parsed, err, deploy)
+	}
+
+	tests := []struct {
+		tick, max, lim string
+	}{
+		{"", "21000000", "1000"},
+		{"cros", "1000", "21000000"},
+		{"cros", "-1", "1"},
+		{"cros", "21000000", "abc"},
+	}
+	for _, test := range tests {
+		if _, _, err := deployPayload(test.tick, test.max, test.lim); err == nil {
+			t.Errorf("deployPayload(%q, %q, %q) returned no error", test.tick, test.max, test.lim)
+		}
+	}
+}
+
+func TestCheckTickNotDeployed(t *testing.T) {
+	idx := indexer.NewMemory()
+	idx.SetTick(indexer.TickInfo{Tick: "CROS"})
+	if err := checkTickNotDeployed(context.Background(), idx, "moon"); err != nil {
+		t.Errorf("check of a new tick = %v, want nil", err)
+	}
+	if err := checkTickNotDeployed(context.Background(), idx, "cros"); err == nil || !strings.Contains(err.Error(), "CROS is already deployed") {
+		t.Errorf("check of a deployed tick = %v, want already deployed", err)
+	}
+	indexerErr := errors.New("indexer is down")
+	if err := checkTickNotDeployed(context.Background(), &tickInfoErrIndexer{Indexer: idx, err: indexerErr}, "moon"); !errors.Is(err, indexerErr) {
+		t.Errorf("check with a failing indexer = %v, want the indexer error", err)
+	}
+}
+
+// tickInfoErrIndexer 查询tick时返回err
+type tickInfoErrIndexer struct {
+	indexer.Indexer
+	err error
+}
+
+func (i *tickInfoErrIndexer) TickInfo(ctx context.Context, tick string) (*indexer.TickInfo, error) {
+	return nil, i.err
+}
@@ -0,0 +1,88 @@
+package cobra
+
+import (
+	"cronos-tools/src/txengine"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"math/big"
+)
+
+// addFeeFlags 添加gas价格相关的参数
+func addFeeFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("dynamic-fee", "", false, "Send EIP-1559 dynamic fee transactions instead of legacy transactions")
+	cmd.Flags().StringP("max-fee", "", "", "Max fee per gas in gwei for dynamic fee transactions,default 2*base fee+priority fee")
+	cmd.Flags().StringP("max-priority-fee", "", "", "Max priority fee per gas in gwei for dynamic fee transactions,default suggested by rpc")
+	cmd.Flags().Float64P("gas-price-multiplier", "", 1, "Multiplier applied to the suggested gas price,default 1")
+	cmd.Flags().StringP("fee-ceiling", "", "", "Pause sending while the gas price in gwei is above this value")
+}
+
+// getFeeConfig 从参数构造txengine.FeeConfig
+func getFeeConfig(cmd *cobra.Command) (txengine.FeeConfig, error) {
+	feeConfig := txengine.DefaultFeeConfig()
+	dynamicFee, err := cmd.Flags().GetBool("dynamic-fee")
+	if err != nil {
+		return feeConfig, err
+	}
+	feeConfig.DynamicFee = dynamicFee
+
+	multiplier, err := cmd.Flags().GetFloat64("gas-price-multiplier")
+	if err != nil {
+		return feeConfig, err
+	}
+	if multiplier <= 0 {
+		return feeConfig, errors.New("gas-price-multiplier must bigger than 0")
+	}
+	feeConfig.GasPriceMultiplier = decimal.NewFromFloat(multiplier)
+
+	if feeConfig.MaxFee, err = getGweiFlag(cmd, "max-fee"); err != nil {
+		return feeConfig, err
+	}
+	if feeConfig.MaxPriorityFee, err = getGweiFlag(cmd, "max-priority-fee"); err != nil {
+		return feeConfig, err
+	}
+	if feeConfig.FeeCeiling, err = getGweiFlag(cmd, "fee-ceiling"); err != nil {
+		return feeConfig, err
+	}
+	if !dynamicFee && (feeConfig.MaxFee != nil || feeConfig.MaxPriorityFee != nil) {
+		return feeConfig, errors.New("max-fee and max-priority-fee require dynamic-fee")
+	}
+	return feeConfig, nil
+}
+
+// getGweiFlag 读取以gwei为单位的参数并转换为wei，未设置时返回nil
+func getGweiFlag(cmd *cobra.Command, name string) (*big.Int, error) {
+	value, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, nil
+	}
+	gwei, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid number: %w", name, err)
+	}
+	if gwei.Sign() <= 0 {
+		return nil, fmt.Errorf("%s must bigger than 0", name)
+	}
+	return gwei.Shift(9).BigInt(), nil
+}
+
+// parseCRO 将以CRO为单位的数量转换为wei
+func parseCRO(value string) (*big.Int, error) {
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid amount: %w", value, err)
+	}
+	if amount.Sign() < 0 {
+		return nil, fmt.Errorf("amount %s must not be negative", value)
+	}
+	return amount.Shift(18).BigInt(), nil
+}
+
+// formatCRO 将wei转换为以CRO为单位的字符串
+func formatCRO(wei *big.Int) string {
+	return decimal.NewFromBigInt(wei, -18).String()
+}
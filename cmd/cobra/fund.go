@@ -0,0 +1,243 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/inscription"
+	"cronos-tools/src/rpcerr"
+	"cronos-tools/src/txengine"
+	"cronos-tools/src/utils"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"log"
+	"math/big"
+	"strings"
+)
+
+var fundCmd = &cobra.Command{
+	Use:   "fund",
+	Short: "Top up native coin of bip-44 sequence addresses from a source address",
+	Long:  `Top up native coin of bip-44 sequence addresses from a source address to a target balance, the target can be estimated from the gas cost of minting`,
+	// 部分地址转账失败时继续转给其他地址，最后以非0状态退出
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := getKeySource(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		rpc, err := cmd.Flags().GetString("rpc")
+		if err != nil {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		if rpc == "" {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		startIndex, err := cmd.Flags().GetUint("start-index")
+		if err != nil {
+			log.Panicln(errors.New("start-index is required"))
+		}
+		endIndex, err := cmd.Flags().GetUint("end-index")
+		if err != nil {
+			log.Panicln(errors.New("end-index is required"))
+		}
+		if startIndex > endIndex {
+			log.Panicln(errors.New("start-index must less than or equal to end-index"))
+		}
+		sourceIndex, err := cmd.Flags().GetUint("source-index")
+		if err != nil {
+			log.Panicln(errors.New("source-index is required"))
+		}
+		target, err := cmd.Flags().GetString("target")
+		if err != nil {
+			log.Panicln(err)
+		}
+		perAddressMinted, err := cmd.Flags().GetUint("per-address-minted")
+		if err != nil {
+			log.Panicln(err)
+		}
+		if target == "" && perAddressMinted == 0 {
+			log.Panicln(errors.New("target or per-address-minted is required"))
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		client, err := dialRPC(cmd, rpc)
+		if err != nil {
+			log.Panicln(err)
+		}
+		networkID, err := client.NetworkID(context.Background())
+		if err != nil {
+			log.Panicln(err)
+		}
+		nonceManager, err := getNonceManager(cmd, client)
+		if err != nil {
+			log.Panicln(err)
+		}
+		feeConfig, err := getFeeConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		gasConfig, err := getGasConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		sourcePrivateKey, err := keys.PrivateKey(sourceIndex)
+		if err != nil {
+			log.Panicln(err)
+		}
+		sender := txengine.NewSender(client, networkID, sourcePrivateKey)
+		sender.Retry = client.Retry
+		sender.Gas = gasConfig
+		sender.Nonces = nonceManager
+		sender.Fees = feeConfig
+		sourceAddress := sender.Address()
+
+		// 计算目标余额
+		fees, err := sender.SuggestFees(context.Background())
+		if err != nil {
+			log.Panicln(err)
+		}
+		var targetBalance *big.Int
+		if target != "" {
+			targetBalance, err = parseCRO(target)
+			if err != nil {
+				log.Panicln(err)
+			}
+		} else {
+			// 预估每个地址mint所需的gas fee
+			mintPayload, err := getFundMintPayload(cmd)
+			if err != nil {
+				log.Panicln(err)
+			}
+			mintGas, err := sender.EstimateGas(context.Background(), sourceAddress, big.NewInt(0), mintPayload)
+			if err != nil {
+				log.Panicln(err)
+			}
+			targetBalance = new(big.Int).Mul(fees.MaxPricePerGas(), new(big.Int).SetUint64(mintGas*uint64(perAddressMinted)))
+		}
+		log.Println("Source index:", sourceIndex, "Address:", sourceAddress.Hex(), "Target balance:", formatCRO(targetBalance), "CRO")
+
+		// 生成转账计划
+		var plans []fundPlan
+		totalAmount := new(big.Int)
+		for i := startIndex; i <= endIndex; i++ {
+			if i == sourceIndex {
+				continue
+			}
+			accountPrivateKey, err := keys.PrivateKey(i)
+			if err != nil {
+				log.Panicln(err)
+			}
+			accountAddress := utils.GetAddressFromPrivateKey(accountPrivateKey)
+			balance, err := client.BalanceAt(context.Background(), accountAddress, nil)
+			if err != nil {
+				log.Panicln(err)
+			}
+			if balance.Cmp(targetBalance) >= 0 {
+				log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Balance:", formatCRO(balance), "CRO", "No need to fund")
+				continue
+			}
+			amount := new(big.Int).Sub(targetBalance, balance)
+			totalAmount.Add(totalAmount, amount)
+			plans = append(plans, fundPlan{accountIndex: i, address: accountAddress, amount: amount})
+			log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Balance:", formatCRO(balance), "CRO", "Fund:", formatCRO(amount), "CRO")
+		}
+		if len(plans) > 0 {
+			// 所有转账都不带数据，估算一次即可
+			if sender.GasLimit, err = sender.EstimateGas(context.Background(), plans[0].address, plans[0].amount, nil); err != nil {
+				log.Panicln(err)
+			}
+		}
+		totalFee := new(big.Int).Mul(fees.MaxPricePerGas(), new(big.Int).SetUint64(sender.GasLimit*uint64(len(plans))))
+		sourceBalance, err := client.BalanceAt(context.Background(), sourceAddress, nil)
+		if err != nil {
+			log.Panicln(err)
+		}
+		log.Println("Accounts to fund:", len(plans), "Total amount:", formatCRO(totalAmount), "CRO", "Max total fee:", formatCRO(totalFee), "CRO", "Source balance:", formatCRO(sourceBalance), "CRO")
+		if dryRun || len(plans) == 0 {
+			return nil
+		}
+		if sourceBalance.Cmp(new(big.Int).Add(totalAmount, totalFee)) < 0 {
+			log.Panicln(errors.New("source balance is not enough to fund all accounts"))
+		}
+
+		// 执行转账，已经发送的交易总是等待结果
+		tracker := newTracker(context.Background(), client, nil, nil)
+		failed := fundAccounts(context.Background(), sender, tracker, plans)
+		waitConfirmations(cmd, tracker, map[common.Address]uint{sourceAddress: sourceIndex})
+		for _, plan := range failed {
+			log.Println("Failed account index:", plan.accountIndex, "Address:", plan.address.Hex(), "Amount:", formatCRO(plan.amount), "CRO")
+		}
+		log.Println("Accounts funded:", len(plans)-len(failed), "Failed:", len(failed))
+		if len(failed) > 0 {
+			return fmt.Errorf("can not fund %d of %d accounts", len(failed), len(plans))
+		}
+		return nil
+	},
+}
+
+// fundPlan 一个地址需要转入的金额
+type fundPlan struct {
+	accountIndex uint
+	address      common.Address
+	amount       *big.Int
+}
+
+// fundAccounts 依次发送转账并交给tracker跟踪，一个地址失败时继续转给其他地址，返回没有发送的转账。
+// 来源地址余额不足以支付gas fee时剩下的转账都不再发送
+func fundAccounts(ctx context.Context, sender *txengine.Sender, tracker *txengine.Tracker, plans []fundPlan) (failed []fundPlan) {
+	var skipErr error
+	for _, plan := range plans {
+		if skipErr != nil {
+			failed = append(failed, plan)
+			log.Println("Account index:", plan.accountIndex, "Address:", plan.address.Hex(), "Skipped:", skipErr)
+			continue
+		}
+		result, err := sender.SendValue(ctx, plan.address, plan.amount, nil)
+		if err != nil {
+			failed = append(failed, plan)
+			log.Println("Account index:", plan.accountIndex, "Address:", plan.address.Hex(), "Can not fund:", err)
+			if rpcerr.PolicyOf(err) == rpcerr.PolicySkipAccount {
+				log.Println("Source address", sender.Address().Hex(), "can not pay for more transfers, skip the remaining accounts")
+				skipErr = err
+			}
+			continue
+		}
+		tracker.Track(sender.Address(), result.Tx, sender.Bump)
+		log.Println("Account index:", plan.accountIndex, "Address:", plan.address.Hex(), "Fund:", formatCRO(plan.amount), "CRO", "Tx hash:", result.Hash.Hex())
+	}
+	return failed
+}
+
+func init() {
+	rootCmd.AddCommand(fundCmd)
+	addKeyFlags(fundCmd)
+	fundCmd.Flags().StringP("rpc", "r", "", "Specify the rpc url, several urls separated by commas fail over to each other")
+	fundCmd.Flags().UintP("source-index", "", 0, "Index of the address paying for the funding,default 0")
+	fundCmd.Flags().UintP("start-index", "s", 0, "Start index of bip-44 sequence addresses,default 0")
+	fundCmd.Flags().UintP("end-index", "e", 0, "End index of bip-44 sequence addresses,default 0")
+	fundCmd.Flags().StringP("target", "", "", "Target native coin balance of each address in CRO")
+	fundCmd.Flags().UintP("per-address-minted", "p", 0, "Estimate the target balance from the gas cost of minting this many inscriptions per address")
+	fundCmd.Flags().StringP("mint-content", "", "", "Text content of the inscription the addresses will mint, used with --per-address-minted,default a "+inscription.Protocol+" mint of the longest tick")
+	fundCmd.Flags().BoolP("dry-run", "", false, "Only print the funding plan")
+	fundCmd.Flags().UintP("max-in-flight", "", 5, "Max number of unconfirmed transactions of the source address,default 5")
+	addFeeFlags(fundCmd)
+	addGasFlags(fundCmd)
+	addConfirmFlags(fundCmd)
+}
+
+// getFundMintPayload 返回--mint-content，未指定时用最长的tick构造一个mint铭文，按最高的gas预估
+func getFundMintPayload(cmd *cobra.Command) ([]byte, error) {
+	content, err := cmd.Flags().GetString("mint-content")
+	if err != nil {
+		return nil, err
+	}
+	if content != "" {
+		return []byte(content), nil
+	}
+	return inscription.Mint{Tick: strings.Repeat("x", inscription.MaxTickLength), Amt: "1000000000"}.Encode()
+}
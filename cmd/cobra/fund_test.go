@@ -0,0 +1,41 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/txengine"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+func TestFundAccounts(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plans := make([]fundPlan, 5)
+	for i := range plans {
+		plans[i] = fundPlan{accountIndex: uint(i + 1), address: common.BigToAddress(big.NewInt(int64(i + 1))), amount: big.NewInt(1000)}
+	}
+	client := &fakeClient{sendErrTo: map[common.Address]error{
+		// 第2个地址失败后继续，第4个地址发送时来源地址余额不足，剩下的不再发送
+		plans[1].address: errors.New("execution reverted"),
+		plans[3].address: errors.New("insufficient funds for gas * price + value"),
+	}}
+	sender := txengine.NewSender(client, big.NewInt(25), key)
+	tracker := txengine.NewTracker(client)
+
+	var failed []fundPlan
+	captureLog(func() { failed = fundAccounts(context.Background(), sender, tracker, plans) })
+	if len(failed) != 3 || failed[0].accountIndex != 2 || failed[1].accountIndex != 4 || failed[2].accountIndex != 5 {
+		t.Errorf("failed = %+v, want account 2, 4 and 5", failed)
+	}
+	if tracker.Pending() != 2 {
+		t.Errorf("tracking %d transfers, want the 2 sent", tracker.Pending())
+	}
+	if len(client.sent) != 4 {
+		t.Errorf("sent %d transactions, want nothing sent after the source ran out of funds", len(client.sent))
+	}
+}
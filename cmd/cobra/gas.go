@@ -0,0 +1,34 @@
+package cobra
+
+import (
+	"cronos-tools/src/txengine"
+	"errors"
+	"github.com/spf13/cobra"
+)
+
+// addGasFlags 添加gas limit估算相关的参数
+func addGasFlags(cmd *cobra.Command) {
+	gasConfig := txengine.DefaultGasConfig()
+	cmd.Flags().Float64P("gas-margin", "", gasConfig.Margin, "Fraction added to the estimated gas limit of transactions carrying a payload,default 0.1")
+	cmd.Flags().Uint64P("max-gas", "", gasConfig.MaxGas, "Refuse transactions whose gas limit would be above this value, 0 for no limit")
+	cmd.Flags().BoolP("estimate-gas", "", gasConfig.CrossCheck, "Cross-check the gas computed from the payload with eth_estimateGas of the rpc")
+}
+
+// getGasConfig 从参数构造txengine.GasConfig
+func getGasConfig(cmd *cobra.Command) (txengine.GasConfig, error) {
+	gasConfig := txengine.DefaultGasConfig()
+	var err error
+	if gasConfig.Margin, err = cmd.Flags().GetFloat64("gas-margin"); err != nil {
+		return gasConfig, err
+	}
+	if gasConfig.Margin < 0 {
+		return gasConfig, errors.New("gas-margin can not be negative")
+	}
+	if gasConfig.MaxGas, err = cmd.Flags().GetUint64("max-gas"); err != nil {
+		return gasConfig, err
+	}
+	if gasConfig.CrossCheck, err = cmd.Flags().GetBool("estimate-gas"); err != nil {
+		return gasConfig, err
+	}
+	return gasConfig, nil
+}
@@ -0,0 +1,105 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/indexer"
+	"errors"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"os/signal"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Scan blocks and build a local crc-20 index for balance and ticks --indexer local",
+	Run: func(cmd *cobra.Command, args []string) {
+		rpc, err := cmd.Flags().GetString("rpc")
+		if err != nil {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		if rpc == "" {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		fromBlock, err := cmd.Flags().GetUint64("from-block")
+		if err != nil {
+			log.Panicln(err)
+		}
+		toBlock, err := cmd.Flags().GetUint64("to-block")
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		local, err := openLocalIndex(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		defer local.Close()
+
+		// 没有指定起始区块时从上次索引的高度继续
+		height, indexed, err := local.Height()
+		if err != nil {
+			log.Panicln(err)
+		}
+		if !cmd.Flags().Changed("from-block") {
+			if !indexed {
+				log.Panicln(errors.New("from-block is required for a new index, use the block the first tick was deployed in"))
+			}
+			fromBlock = height + 1
+		} else if indexed && fromBlock != height+1 {
+			log.Println("Warning: index is at block", height, "but scanning from", fromBlock, ", balances may be counted twice or miss blocks")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		client, err := dialRPC(cmd, rpc)
+		if err != nil {
+			log.Panicln(err)
+		}
+		if toBlock == 0 {
+			toBlock, err = client.BlockNumber(ctx)
+			if err != nil {
+				log.Panicln(err)
+			}
+		}
+		if fromBlock > toBlock {
+			log.Println("Index is up to date at block", height)
+			return
+		}
+
+		scanner, err := indexer.NewScanner(ctx, client, local)
+		if err != nil {
+			log.Panicln(err)
+		}
+		scanner.OnBlock = func(number uint64, statuses []indexer.TxStatus) {
+			for _, status := range statuses {
+				if status.Valid {
+					log.Println("Block:", number, "Tx hash:", status.Hash.Hex(), "Op:", status.Op, "Tick:", status.Tick, "Amt:", status.Amt)
+				} else {
+					log.Println("Block:", number, "Tx hash:", status.Hash.Hex(), "Invalid:", status.Reason)
+				}
+			}
+			if number%1000 == 0 {
+				log.Println("Indexed block", number, "of", toBlock)
+			}
+		}
+		log.Println("Indexing block", fromBlock, "to", toBlock)
+		if err := scanner.Scan(ctx, fromBlock, toBlock); err != nil {
+			if errors.Is(err, context.Canceled) {
+				height, _, _ := local.Height()
+				log.Println("Interrupted, index is at block", height, ", run index again to continue")
+				return
+			}
+			log.Panicln(err)
+		}
+		log.Println("Indexed to block", toBlock)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.Flags().StringP("rpc", "r", "", "Specify the rpc url, several urls separated by commas fail over to each other")
+	indexCmd.Flags().Uint64P("from-block", "", 0, "First block to scan, default continue after the last indexed block")
+	indexCmd.Flags().Uint64P("to-block", "", 0, "Last block to scan, default the latest block")
+}
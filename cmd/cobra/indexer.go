@@ -0,0 +1,67 @@
+package cobra
+
+import (
+	"cronos-tools/src/indexer"
+	"fmt"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+// newIndexer 根据--indexer创建索引服务，测试时可以替换为indexer.Memory
+var newIndexer = func(cmd *cobra.Command) (indexer.Indexer, error) {
+	kind, err := cmd.Flags().GetString("indexer")
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case "croscribe":
+		indexerURL, err := cmd.Flags().GetString("indexer-url")
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := cmd.Flags().GetDuration("indexer-timeout")
+		if err != nil {
+			return nil, err
+		}
+		cacheTTL, err := cmd.Flags().GetDuration("cache-ttl")
+		if err != nil {
+			return nil, err
+		}
+		perSecond, err := cmd.Flags().GetFloat64("indexer-rate")
+		if err != nil {
+			return nil, err
+		}
+		policy, err := getRetryPolicy(cmd)
+		if err != nil {
+			return nil, err
+		}
+		limited := indexer.NewLimited(indexer.NewCroscribe(indexerURL, timeout), perSecond, 1)
+		limited.Retry = policy
+		if cacheTTL <= 0 {
+			return limited, nil
+		}
+		return indexer.NewCache(limited, indexer.DefaultCacheDir(), indexerURL, cacheTTL), nil
+	case "local":
+		return openLocalIndex(cmd)
+	default:
+		return nil, fmt.Errorf("unknown indexer %q, must be croscribe or local", kind)
+	}
+}
+
+// openLocalIndex 打开--index-db指定的本地索引数据库
+func openLocalIndex(cmd *cobra.Command) (*indexer.Local, error) {
+	path, err := cmd.Flags().GetString("index-db")
+	if err != nil {
+		return nil, err
+	}
+	return indexer.OpenLocal(path)
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringP("indexer", "", "croscribe", "Where to read inscription balances and ticks, croscribe or local (built by the index command)")
+	rootCmd.PersistentFlags().StringP("indexer-url", "", indexer.DefaultCroscribeURL, "Base url of the inscription indexer api")
+	rootCmd.PersistentFlags().DurationP("indexer-timeout", "", 30*time.Second, "Timeout of each indexer request,default 30s")
+	rootCmd.PersistentFlags().Float64P("indexer-rate", "", 5, "Max indexer api requests per second, 0 for no limit")
+	rootCmd.PersistentFlags().DurationP("cache-ttl", "", time.Minute, "How long the tick list from the indexer api is cached, 0 disables the cache")
+	rootCmd.PersistentFlags().StringP("index-db", "", indexer.DefaultLocalPath(), "Path of the local index database")
+}
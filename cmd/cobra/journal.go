@@ -0,0 +1,183 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/journal"
+	"cronos-tools/src/rpcerr"
+	"cronos-tools/src/txengine"
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// journalParams 写入任务日志、恢复任务时沿用的参数，只包括决定任务内容的参数。密钥来源、配置文件、
+// 索引服务地址等可能包含敏感信息的参数不写入，恢复任务时需要重新指定
+var journalParams = map[string]bool{
+	"tick":                 true,
+	"amt":                  true,
+	"hex-content":          true,
+	"text-content":         true,
+	"allow-non-crc20":      true,
+	"per-address-minted":   true,
+	"start-index":          true,
+	"end-index":            true,
+	"hd-path":              true,
+	"collector":            true,
+	"concurrency":          true,
+	"concurrent":           true,
+	"max-in-flight":        true,
+	"broadcast":            true,
+	"rpc":                  true,
+	"stop-at-progress":     true,
+	"progress-interval":    true,
+	"deploy-tx":            true,
+	"allow-unknown-lim":    true,
+	"dynamic-fee":          true,
+	"gas-price-multiplier": true,
+	"max-fee":              true,
+	"max-priority-fee":     true,
+	"fee-ceiling":          true,
+	"estimate-gas":         true,
+	"gas-margin":           true,
+	"max-gas":              true,
+	"confirm-timeout":      true,
+}
+
+// addJournalFlags 添加任务日志相关的参数
+func addJournalFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("resume", "", "", "Resume an interrupted job by its job id")
+	cmd.Flags().StringP("journal-dir", "", journal.DefaultDir(), "Directory of job journals")
+}
+
+// publicRPC 判断逗号分隔的rpc地址中是否都没有用户名密码和查询参数，这两处常带有api key
+func publicRPC(rpc string) bool {
+	for _, rawURL := range strings.Split(rpc, ",") {
+		u, err := url.Parse(strings.TrimSpace(rawURL))
+		if err != nil || u.User != nil || u.RawQuery != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// openJournal 创建新的任务日志，或者在指定--resume时打开已有任务日志。
+// 恢复任务时，命令行没有指定的参数使用任务日志中保存的值，即使配置文件中设置了该参数。
+func openJournal(cmd *cobra.Command) (*journal.Journal, map[uint]*journal.AccountProgress, error) {
+	dir, err := cmd.Flags().GetString("journal-dir")
+	if err != nil {
+		return nil, nil, err
+	}
+	resume, err := cmd.Flags().GetString("resume")
+	if err != nil {
+		return nil, nil, err
+	}
+	if resume == "" {
+		params := make(map[string]string)
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			if !journalParams[flag.Name] {
+				return
+			}
+			if flag.Name == "rpc" && !publicRPC(flag.Value.String()) {
+				log.Println("The rpc url carries credentials and is not written to the journal, set --rpc again on resume")
+				return
+			}
+			params[flag.Name] = flag.Value.String()
+		})
+		jobJournal, err := journal.Create(dir, cmd.Name(), params)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Println("Job id:", jobJournal.ID(), "resume with --resume", jobJournal.ID())
+		return jobJournal, map[uint]*journal.AccountProgress{}, nil
+	}
+
+	jobJournal, records, err := journal.Open(dir, resume)
+	if err != nil {
+		return nil, nil, err
+	}
+	if records[0].Command != cmd.Name() {
+		jobJournal.Close()
+		return nil, nil, fmt.Errorf("%w: %s", journal.ErrCommandMismatch, records[0].Command)
+	}
+	for name, value := range records[0].Params {
+		// 命令行指定的参数优先，配置文件中的值让位于任务日志
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || (flag.Changed && !fromConfig(flag)) {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			jobJournal.Close()
+			return nil, nil, fmt.Errorf("can not restore %s from journal: %w", name, err)
+		}
+	}
+	progress, err := journal.Replay(records)
+	if err != nil {
+		jobJournal.Close()
+		return nil, nil, err
+	}
+	log.Println("Resume job:", jobJournal.ID())
+	return jobJournal, progress, nil
+}
+
+// checkResumedAddresses 恢复任务时确认账户序号推导出的地址与任务日志一致，避免换了密钥来源后
+// 把进度套用到其他地址上
+func checkResumedAddresses(keys wallet.KeySource, progress map[uint]*journal.AccountProgress) error {
+	return journal.CheckAddresses(progress, func(accountIndex uint) (common.Address, error) {
+		privateKey, err := keys.PrivateKey(accountIndex)
+		if err != nil {
+			return common.Address{}, err
+		}
+		return utils.GetAddressFromPrivateKey(privateKey), nil
+	})
+}
+
+// recoverUnsent 恢复任务时处理已签名但没有发送记录的交易。已经上链或者重新发送后节点接受的交易
+// 记为已发送并继续跟踪，避免重复mint；节点拒绝的交易当作没有发送
+func recoverUnsent(ctx context.Context, client txengine.ReceiptClient, jobJournal *journal.Journal, p *journal.AccountProgress) {
+	for nonce, tx := range p.Unsent {
+		delete(p.Unsent, nonce)
+		if err := resendUnsent(ctx, client, tx); err != nil {
+			log.Println("Account index:", p.AccountIndex, "Address:", p.Address.Hex(), "Nonce:", nonce, "Tx hash:", tx.Hash().Hex(), "Signed transaction was not sent:", err)
+			continue
+		}
+		log.Println("Account index:", p.AccountIndex, "Address:", p.Address.Hex(), "Nonce:", nonce, "Tx hash:", tx.Hash().Hex(), "Recovered signed transaction")
+		p.Pending[nonce] = tx
+		if err := jobJournal.Sent(p.AccountIndex, p.Address, tx); err != nil {
+			log.Println("Can not write journal", err)
+		}
+	}
+}
+
+// journaledBump 包装sender.Bump，替换交易和原交易一样记录到任务日志：签名后由sender.OnSigned
+// 记录，节点接受后记为已发送，恢复任务时跟踪替换交易而不是重新mint
+func journaledBump(jobJournal *journal.Journal, sender *txengine.Sender, accountIndex uint) txengine.BumpFunc {
+	return func(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+		replacement, err := sender.Bump(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		if err := jobJournal.Sent(accountIndex, sender.Address(), replacement); err != nil {
+			log.Println("Can not write journal", err)
+		}
+		return replacement, nil
+	}
+}
+
+// resendUnsent 交易已有回执时直接返回，否则重新发送同一笔交易，节点已有该交易时同样视为成功
+func resendUnsent(ctx context.Context, client txengine.ReceiptClient, tx *types.Transaction) error {
+	if receipt, err := client.TransactionReceipt(ctx, tx.Hash()); err == nil && receipt != nil {
+		return nil
+	}
+	err := client.SendTransaction(ctx, tx)
+	if err != nil && rpcerr.PolicyOf(err) != rpcerr.PolicyTreatAsSent {
+		return err
+	}
+	return nil
+}
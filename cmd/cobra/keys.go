@@ -0,0 +1,133 @@
+package cobra
+
+import (
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"errors"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// addKeyFlags 添加账户私钥来源相关的参数
+func addKeyFlags(cmd *cobra.Command) {
+	addMnemonicFlags(cmd)
+	cmd.Flags().StringP("keystore", "", "", "Use a keystore file or directory instead of mnemonic, index i is the i-th file")
+}
+
+// addMnemonicFlags 添加助记词来源和派生路径相关的参数
+func addMnemonicFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("mnemonic", "m", "", "Set mnemonic, prefer --mnemonic-file, --vault or env "+wallet.MnemonicEnv)
+	cmd.Flags().StringP("mnemonic-file", "", "", "Read mnemonic from file, - for stdin")
+	cmd.Flags().BoolP("vault", "", false, "Read mnemonic from the encrypted local vault")
+	cmd.Flags().StringP("vault-path", "", wallet.DefaultVaultPath(), "Path of the encrypted local vault")
+	cmd.Flags().StringP("hd-path", "", utils.DefaultHDPath, "HD derivation path template, {index} is replaced by the account index, e.g. "+utils.LedgerLiveHDPath+" for Ledger Live")
+	cmd.Flags().BoolP("bip39-passphrase", "", false, "Prompt for the BIP-39 passphrase of the mnemonic, or set env "+wallet.BIP39PassphraseEnv)
+}
+
+// getKeySource 按--keystore、--vault、--mnemonic-file、--mnemonic、环境变量的顺序选择私钥来源
+func getKeySource(cmd *cobra.Command) (wallet.KeySource, error) {
+	keystorePath, err := cmd.Flags().GetString("keystore")
+	if err != nil {
+		return nil, err
+	}
+	if keystorePath != "" {
+		passphrase, err := readPassphrase("Keystore passphrase: ", false)
+		if err != nil {
+			return nil, err
+		}
+		return wallet.NewKeystoreSource(keystorePath, passphrase)
+	}
+	return getMnemonicSource(cmd)
+}
+
+// getMnemonicSource 从助记词、--hd-path和BIP-39密码创建私钥来源
+func getMnemonicSource(cmd *cobra.Command) (*wallet.MnemonicSource, error) {
+	mnemonic, err := getMnemonic(cmd)
+	if err != nil {
+		return nil, err
+	}
+	hdPath, err := cmd.Flags().GetString("hd-path")
+	if err != nil {
+		return nil, err
+	}
+	bip39Passphrase, err := getBIP39Passphrase(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.NewMnemonicSource(mnemonic, bip39Passphrase, hdPath)
+}
+
+// getBIP39Passphrase 从环境变量读取BIP-39密码，未设置时在指定--bip39-passphrase时提示输入，
+// 不从命令行参数读取，避免留在shell历史和进程列表中
+func getBIP39Passphrase(cmd *cobra.Command) (string, error) {
+	if passphrase, ok := os.LookupEnv(wallet.BIP39PassphraseEnv); ok {
+		return passphrase, nil
+	}
+	usePassphrase, err := cmd.Flags().GetBool("bip39-passphrase")
+	if err != nil {
+		return "", err
+	}
+	if !usePassphrase {
+		return "", nil
+	}
+	return prompt.Stdin.PromptPassword("BIP-39 passphrase: ")
+}
+
+// getMnemonic 从--vault、--mnemonic-file、--mnemonic或环境变量读取助记词
+func getMnemonic(cmd *cobra.Command) (string, error) {
+	useVault, err := cmd.Flags().GetBool("vault")
+	if err != nil {
+		return "", err
+	}
+	if useVault {
+		vaultPath, err := cmd.Flags().GetString("vault-path")
+		if err != nil {
+			return "", err
+		}
+		passphrase, err := readPassphrase("Vault passphrase: ", false)
+		if err != nil {
+			return "", err
+		}
+		return wallet.OpenVault(vaultPath, passphrase)
+	}
+	mnemonicFile, err := cmd.Flags().GetString("mnemonic-file")
+	if err != nil {
+		return "", err
+	}
+	if mnemonicFile != "" {
+		return wallet.ReadMnemonicFile(mnemonicFile)
+	}
+	mnemonic, err := cmd.Flags().GetString("mnemonic")
+	if err != nil {
+		return "", err
+	}
+	if mnemonic != "" {
+		return mnemonic, nil
+	}
+	if mnemonic = os.Getenv(wallet.MnemonicEnv); mnemonic != "" {
+		return mnemonic, nil
+	}
+	return "", errors.New("mnemonic is required")
+}
+
+// readPassphrase 从环境变量读取密码，未设置时在终端提示输入。confirm为true时需要输入两次
+func readPassphrase(message string, confirm bool) (string, error) {
+	if passphrase, ok := os.LookupEnv(wallet.PassphraseEnv); ok {
+		return passphrase, nil
+	}
+	passphrase, err := prompt.Stdin.PromptPassword(message)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		repeat, err := prompt.Stdin.PromptPassword("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if repeat != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
@@ -0,0 +1,113 @@
+package cobra
+
+import (
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetKeySource(t *testing.T) {
+	dir := t.TempDir()
+	mnemonics := map[string]string{
+		"vault":         "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"mnemonic-file": "letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		"mnemonic":      testMnemonic,
+		"env":           "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+	}
+	want := map[string]common.Address{}
+	for source, mnemonic := range mnemonics {
+		keys, err := wallet.NewMnemonicSource(mnemonic, "", utils.DefaultHDPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[source] = testAddress(t, keys, 0)
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want["keystore"] = crypto.PubkeyToAddress(key.PublicKey)
+	keystorePath := filepath.Join(dir, "keystore.json")
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{Address: want["keystore"], PrivateKey: key}, "secret", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keystorePath, keyJSON, 0600); err != nil {
+		t.Fatal(err)
+	}
+	vaultPath := filepath.Join(dir, "vault.json")
+	if err := wallet.SaveVault(vaultPath, mnemonics["vault"], "secret", false); err != nil {
+		t.Fatal(err)
+	}
+	mnemonicPath := filepath.Join(dir, "mnemonic.txt")
+	if err := os.WriteFile(mnemonicPath, []byte(mnemonics["mnemonic-file"]+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// 密码从环境变量读取，不提示输入
+	t.Setenv(wallet.PassphraseEnv, "secret")
+	t.Setenv(wallet.BIP39PassphraseEnv, "")
+
+	all := map[string]string{"keystore": keystorePath, "vault": "true", "vault-path": vaultPath, "mnemonic-file": mnemonicPath, "mnemonic": mnemonics["mnemonic"]}
+	// 每个来源优先于后面的来源
+	tests := []struct {
+		flags []string
+		env   bool
+		want  string
+	}{
+		{[]string{"keystore", "vault", "vault-path", "mnemonic-file", "mnemonic"}, true, "keystore"},
+		{[]string{"vault", "vault-path", "mnemonic-file", "mnemonic"}, true, "vault"},
+		{[]string{"mnemonic-file", "mnemonic"}, true, "mnemonic-file"},
+		{[]string{"mnemonic"}, true, "mnemonic"},
+		{nil, true, "env"},
+		{nil, false, ""},
+	}
+	for _, test := range tests {
+		cmd := &cobra.Command{Use: "test"}
+		addKeyFlags(cmd)
+		for _, name := range test.flags {
+			if err := cmd.Flags().Set(name, all[name]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		env := ""
+		if test.env {
+			env = mnemonics["env"]
+		}
+		t.Setenv(wallet.MnemonicEnv, env)
+
+		keys, err := getKeySource(cmd)
+		if test.want == "" {
+			if err == nil || !strings.Contains(err.Error(), "mnemonic is required") {
+				t.Errorf("getKeySource without a source = %v, want mnemonic is required", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("getKeySource with %v: %v", test.flags, err)
+			continue
+		}
+		if address := testAddress(t, keys, 0); address != want[test.want] {
+			t.Errorf("getKeySource with %v uses %s, want the %s key %s", test.flags, address.Hex(), test.want, want[test.want].Hex())
+		}
+	}
+
+	// vault密码错误时不回退到其他来源
+	t.Setenv(wallet.PassphraseEnv, "wrong")
+	cmd := &cobra.Command{Use: "test"}
+	addKeyFlags(cmd)
+	for _, name := range []string{"vault", "vault-path", "mnemonic"} {
+		if err := cmd.Flags().Set(name, all[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := getKeySource(cmd); err == nil {
+		t.Error("getKeySource with a wrong vault passphrase returned no error")
+	}
+}
@@ -4,18 +4,20 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cobra
 
 import (
+	"bytes"
 	"context"
-	"cronos-tools/src/utils"
+	"cronos-tools/src/inscription"
+	"cronos-tools/src/journal"
+	"cronos-tools/src/rpcerr"
+	"cronos-tools/src/txengine"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	_ "github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/shopspring/decimal"
 	"log"
+	"math/big"
 	"strings"
-	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -26,13 +28,26 @@ var mintCmd = &cobra.Command{
 	Short: "Auto mint inscriptions through mnemonic with multi bip-44 sequence addresses",
 	Long:  `Auto mint inscriptions through mnemonic with multi bip-44 sequence addresses, you must support enough native coin to pay for gas fee`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("mint called")
-		mnemonic, err := cmd.Flags().GetString("mnemonic")
+		log.Println("mint called")
+		jobJournal, progress, err := openJournal(cmd)
 		if err != nil {
-			log.Panicln(errors.New("mnemonic is required"))
+			log.Panicln(err)
+		}
+		defer jobJournal.Close()
+		concurrent, err := cmd.Flags().GetBool("concurrent")
+		if err != nil {
+			log.Panicln(err)
+		}
+		if concurrent {
+			asyncMint(cmd, jobJournal, progress)
+			return
+		}
+		keys, err := getKeySource(cmd)
+		if err != nil {
+			log.Panicln(err)
 		}
-		if mnemonic == "" {
-			log.Panicln(errors.New("mnemonic is required"))
+		if err := checkResumedAddresses(keys, progress); err != nil {
+			log.Panicln(err)
 		}
 		rpc, err := cmd.Flags().GetString("rpc")
 		if err != nil {
@@ -55,19 +70,10 @@ var mintCmd = &cobra.Command{
 			log.Panicln(errors.New("start-index must less than or equal to end-index"))
 		}
 
-		hexContent, err := cmd.Flags().GetString("hex-content")
+		payload, err := getMintPayload(cmd)
 		if err != nil {
-			log.Panicln(errors.New("hex-content is required"))
-		}
-		hexContent = strings.TrimPrefix(hexContent, "0x")
-		textContent, err := cmd.Flags().GetString("text-content")
-		if err != nil {
-			log.Panicln(errors.New("text-content is required"))
-		}
-		if hexContent == "" && textContent == "" {
-			log.Panicln(errors.New("hex-content or text-content is required"))
+			log.Panicln(err)
 		}
-		useHexContent := hexContent != ""
 
 		perAddressMinted, err := cmd.Flags().GetUint("per-address-minted")
 		if err != nil {
@@ -77,164 +83,242 @@ var mintCmd = &cobra.Command{
 			log.Panicln(errors.New("per-address-minted must bigger than 0"))
 		}
 
-		client, err := ethclient.Dial(rpc)
+		client, err := dialRPC(cmd, rpc)
 		if err != nil {
 			log.Panicln(err)
 		}
+		// 同时发送到所有节点，抢先进入mempool
+		if client.FanOut, err = cmd.Flags().GetBool("broadcast"); err != nil {
+			log.Panicln(err)
+		}
 
 		networkID, err := client.NetworkID(context.Background())
 		if err != nil {
 			log.Panicln(err)
 		}
-		gasLimit := uint64(22000)
+		nonceManager, err := getNonceManager(cmd, client)
+		if err != nil {
+			log.Panicln(err)
+		}
+		feeConfig, err := getFeeConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		gasConfig, err := getGasConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
 
-	batchMint:
-		for i := startIndex; i <= endIndex; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if !startMintCap(ctx, cancel, cmd, client, payload) {
+			return
+		}
+		stream := newTxStream(cmd)
+		run := &mintRun{
+			payload:          payload,
+			perAddressMinted: perAddressMinted,
+			tracker:          newTracker(context.Background(), client, jobJournal, stream),
+			client:           client,
+			journal:          jobJournal,
+			stream:           stream,
+			progress:         progress,
+		}
+		accountIndexes := make(map[common.Address]uint)
+		for i := startIndex; i <= endIndex && ctx.Err() == nil; i++ {
 			// 获取当前账户的私钥
-			accountPrivateKey := utils.GetPrivateKey(mnemonic, i)
-			// 获取当前账户的地址
-			accountAddress := utils.GetAddressFromPrivateKey(accountPrivateKey)
-			// 获取当前账户的nonce
-			localNonce, err := client.PendingNonceAt(context.Background(), accountAddress)
+			accountPrivateKey, err := keys.PrivateKey(i)
 			if err != nil {
-				log.Println("Can not get nonce ", err)
-				// 如果获取nonce失败，则等待10秒后
-				for j := 0; j < 5; j++ {
-					time.Sleep(10 * time.Second)
-					localNonce, err = client.PendingNonceAt(context.Background(), accountAddress)
-					if err == nil {
-						continue
-					}
-				}
-				if err != nil {
-					log.Panicln("Can not get nonce after retry 5 times ", err)
-				}
+				log.Panicln(err)
 			}
-			for j := uint(0); j < perAddressMinted; j++ {
-				// 获取当前账户的gasPrice
-				gasPrice, err := client.SuggestGasPrice(context.Background())
-				if err != nil {
-					log.Println("Can not get gas price ", err)
-					// 如果获取gasPrice失败，则等待10秒后
-					for x := 0; x < 5; x++ {
-						time.Sleep(10 * time.Second)
-						gasPrice, err = client.SuggestGasPrice(context.Background())
-						if err == nil {
-							continue
-						}
-					}
-					if err != nil {
-						log.Panicln("Can not get gas price after retry 5 times ", err)
-					}
-				}
-				bufferedGasPrice := decimal.NewFromBigInt(gasPrice, 0).Mul(decimal.NewFromFloat32(1)).BigInt()
-				// 构造payload
-				var payload []byte
-				if useHexContent {
-					payload, err = hex.DecodeString(hexContent)
-					if err != nil {
-						log.Panicln(err)
-					}
-				} else {
-					payload = []byte(textContent)
-				}
-
-				// 检查当前账户的native coin余额是否足够支付gas fee
-				balance, err := client.BalanceAt(context.Background(), accountAddress, nil)
-				if err != nil {
-					log.Println("Can not get balance ", err)
-					// 如果获取balance失败，则等待10秒后
-					for y := 0; y < 5; y++ {
-						time.Sleep(10 * time.Second)
-						balance, err = client.BalanceAt(context.Background(), accountAddress, nil)
-						if err == nil {
-							continue
-						}
-					}
-					if err != nil {
-						log.Panicln("Can not get balance after retry 5 times ", err)
-					}
-				}
-
-				// 计算gas fee
-				gasFee := decimal.NewFromBigInt(bufferedGasPrice, 0).Mul(decimal.NewFromInt(int64(gasLimit))).BigInt()
-				if balance.Cmp(gasFee) < 0 {
-					log.Println("Account " + accountAddress.Hex() + " balance is not enough to pay for gas fee")
-					log.Println("Switch to next account")
-					break
-				}
-				// 构造交易
-				tx := types.NewTx(&types.LegacyTx{
-					Nonce:    localNonce,
-					To:       &accountAddress,
-					Value:    decimal.Zero.BigInt(),
-					Gas:      gasLimit,
-					GasPrice: bufferedGasPrice,
-					Data:     payload,
-				})
-				// 签名交易
-				signedTx, err := types.SignTx(tx, types.NewEIP155Signer(networkID), accountPrivateKey)
-				if err != nil {
-					log.Panicln("Can not sign transaction ", err)
-				}
-				// 发送交易
-				err = client.SendTransaction(context.Background(), signedTx)
-				if err != nil {
-					if strings.Contains(err.Error(), "invalid sequence") {
-						time.Sleep(3 * time.Second)
-						j--
-						continue
-					}
-					if strings.Contains(err.Error(), "tx already in mempool") {
-						log.Println("Account index: ", i, " Address: ", accountAddress.Hex(), " Tx already in mempool, continue retry")
-						continue
-					}
-					if strings.Contains(err.Error(), "insufficient funds") {
-						log.Println("Account index: ", i, " Address: ", accountAddress.Hex(), " Balance is not enough to pay for gas fee and switch to next account")
-						continue batchMint
-					}
-					log.Panicln(err)
-				}
-				txHash := signedTx.Hash()
-				txHashString := txHash.Hex()
-
-				log.Println("Account index: ", i, " Address: ", accountAddress.Hex(), " Tx hash: ", txHashString, " Payload: ", string(payload))
-
-				time.Sleep(3 * time.Second)
-				localNonce++
-				retryTimes := 0
-				maxRetryTimes := 10
-				for {
-					remoteNonce, err := client.PendingNonceAt(context.Background(), accountAddress)
-					if err != nil {
-						log.Panicln(err)
-					}
-					if remoteNonce == localNonce {
-						break
-					} else {
-						time.Sleep(5 * time.Second)
-						retryTimes++
-						if retryTimes > maxRetryTimes {
-							log.Println("Can not get remote nonce after retry " + string(rune(maxRetryTimes)) + " times")
-							log.Println("Switch to next account")
-							continue batchMint
-						}
-					}
-				}
+			sender := txengine.NewSender(client, networkID, accountPrivateKey)
+			sender.Retry = client.Retry
+			sender.Gas = gasConfig
+			sender.Nonces = nonceManager
+			sender.Fees = feeConfig
+			accountIndexes[sender.Address()] = i
+			_, _, err = run.mintAccount(ctx, sender, i)
+			if err != nil {
+				log.Panicln(err)
 			}
 		}
+		waitConfirmations(cmd, run.tracker, accountIndexes)
+		stream.flush()
+		logBroadcastStats(client)
 		log.Println("Mint finished")
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(mintCmd)
-	mintCmd.Flags().StringP("mnemonic", "m", "", "Set mnemonic")
-	mintCmd.Flags().StringP("rpc", "r", "", "Set rpc")
+	addKeyFlags(mintCmd)
+	mintCmd.Flags().StringP("rpc", "r", "", "Set rpc, several urls separated by commas fail over to each other")
 	mintCmd.Flags().StringP("hex-content", "", "", "Set inscriptions with hex content")
 	mintCmd.Flags().StringP("text-content", "", "", "Set inscriptions with text content")
+	mintCmd.Flags().BoolP("allow-non-crc20", "", false, "Send --text-content or --hex-content json that is not a valid "+inscription.Protocol+" inscription")
+	mintCmd.Flags().StringP("tick", "t", "", "Mint a "+inscription.Protocol+" tick, used with --amt instead of --text-content")
+	mintCmd.Flags().StringP("amt", "", "", "Amount of each "+inscription.Protocol+" mint, used with --tick")
 	mintCmd.Flags().UintP("per-address-minted", "p", 10, "Each address can mint how many inscriptions,default 10")
 	mintCmd.Flags().UintP("start-index", "s", 0, "Start index of bip-44 sequence addresses,default 0")
 	mintCmd.Flags().UintP("end-index", "e", 0, "End index of bip-44 sequence addresses,default 0")
+	mintCmd.Flags().BoolP("concurrent", "", false, "Mint with one worker per address concurrently")
+	mintCmd.Flags().UintP("concurrency", "", 10, "Max number of addresses minting at the same time in concurrent mode,default 10")
+	mintCmd.Flags().BoolP("broadcast", "", true, "Send each transaction to all --rpc urls at the same time")
+	mintCmd.Flags().UintP("max-in-flight", "", 5, "Max number of unconfirmed transactions per address,default 5")
+	addFeeFlags(mintCmd)
+	addGasFlags(mintCmd)
+	addConfirmFlags(mintCmd)
+	addJournalFlags(mintCmd)
+	addMintCapFlags(mintCmd)
+}
+
+// getNonceManager 根据--max-in-flight创建本地nonce管理器
+func getNonceManager(cmd *cobra.Command, client txengine.NonceClient) (*txengine.NonceManager, error) {
+	maxInFlight, err := cmd.Flags().GetUint("max-in-flight")
+	if err != nil {
+		return nil, errors.New("max-in-flight is required")
+	}
+	if maxInFlight == 0 {
+		return nil, errors.New("max-in-flight must bigger than 0")
+	}
+	return txengine.NewNonceManager(client, int(maxInFlight)), nil
+}
+
+// getMintPayload 从--tick和--amt、--hex-content或--text-content构造payload
+func getMintPayload(cmd *cobra.Command) ([]byte, error) {
+	tick, err := cmd.Flags().GetString("tick")
+	if err != nil {
+		return nil, errors.New("tick is required")
+	}
+	amt, err := cmd.Flags().GetString("amt")
+	if err != nil {
+		return nil, errors.New("amt is required")
+	}
+	hexContent, err := cmd.Flags().GetString("hex-content")
+	if err != nil {
+		return nil, errors.New("hex-content is required")
+	}
+	hexContent = strings.TrimPrefix(hexContent, "0x")
+	textContent, err := cmd.Flags().GetString("text-content")
+	if err != nil {
+		return nil, errors.New("text-content is required")
+	}
+	if tick != "" || amt != "" {
+		if hexContent != "" || textContent != "" {
+			return nil, errors.New("tick and amt can not be used with hex-content or text-content")
+		}
+		return inscription.Mint{Tick: strings.TrimSpace(tick), Amt: strings.TrimSpace(amt)}.Encode()
+	}
+	if hexContent == "" && textContent == "" {
+		return nil, errors.New("tick and amt, hex-content or text-content is required")
+	}
+	payload := []byte(textContent)
+	if hexContent != "" {
+		payload, err = hex.DecodeString(hexContent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// crc-20铭文在发送前校验，避免无效铭文浪费gas。以data:,{开头的内容视为JSON铭文，
+	// 不完整的JSON和拼错的协议名同样拒绝，除非指定--allow-non-crc20
+	_, err = inscription.Parse(payload)
+	if err == nil {
+		return payload, nil
+	}
+	allowNonCRC20, flagErr := cmd.Flags().GetBool("allow-non-crc20")
+	if flagErr != nil {
+		return nil, flagErr
+	}
+	notCRC20 := errors.Is(err, inscription.ErrNoPrefix) || errors.Is(err, inscription.ErrNotCRC20)
+	if notCRC20 && (allowNonCRC20 || !isJSONInscription(payload)) {
+		return payload, nil
+	}
+	return nil, fmt.Errorf("invalid inscription %s: %w", string(payload), err)
+}
+
+// isJSONInscription 判断内容是否以data:,{开头
+func isJSONInscription(payload []byte) bool {
+	if !bytes.HasPrefix(payload, []byte(inscription.Prefix)) {
+		return false
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(payload[len(inscription.Prefix):]), []byte("{"))
+}
+
+// mintRun 一次mint任务中所有账户共享的状态
+type mintRun struct {
+	payload          []byte
+	perAddressMinted uint
+	tracker          *txengine.Tracker
+	// client 恢复任务时查询已签名交易的回执
+	client  txengine.ReceiptClient
+	journal *journal.Journal
+	stream  *txStream
+	// progress 恢复任务时每个账户已完成的进度
+	progress map[uint]*journal.AccountProgress
+}
 
+// mintAccount sends perAddressMinted self transfers carrying payload from the sender's account,
+// minus those a resumed journal already has, and hands every sent transaction to the tracker.
+// It returns how many transactions were sent and how many failed, and a non-nil error only for
+// failures that should stop the whole run.
+func (r *mintRun) mintAccount(ctx context.Context, sender *txengine.Sender, accountIndex uint) (succeeded uint, failed uint, err error) {
+	accountAddress := sender.Address()
+	r.stream.account(accountIndex, accountAddress)
+	// 发送前记录已签名的交易，进程在发送时中断也不会在恢复时重复mint
+	sender.OnSigned = func(tx *types.Transaction) {
+		if err := r.journal.Signed(accountIndex, accountAddress, tx); err != nil {
+			log.Println("Can not write journal", err)
+		}
+	}
+	count := r.perAddressMinted
+	if p, ok := r.progress[accountIndex]; ok {
+		recoverUnsent(ctx, r.client, r.journal, p)
+		// 继续跟踪上次已发送但没有结果的交易
+		for _, tx := range p.Pending {
+			r.tracker.Track(accountAddress, tx, journaledBump(r.journal, sender, accountIndex))
+		}
+		if p.Done() >= count {
+			log.Println("Account index: ", accountIndex, " Address: ", accountAddress.Hex(), " Already minted in journal, skip")
+			return 0, 0, nil
+		}
+		count -= p.Done()
+	}
+	// 每个账户发送相同的铭文，只估算一次gas limit，超过--max-gas时停止
+	gasLimit, err := sender.EstimateGas(ctx, accountAddress, big.NewInt(0), r.payload)
+	if err != nil {
+		return 0, 0, err
+	}
+	sender.GasLimit = gasLimit
+	if err := r.journal.Planned(accountIndex, accountAddress, count); err != nil {
+		log.Println("Can not write journal", err)
+	}
+	for j := uint(0); j < count; j++ {
+		if ctx.Err() != nil {
+			// 已达到mint进度上限
+			return succeeded, failed, nil
+		}
+		result, err := sender.Send(ctx, accountAddress, r.payload)
+		if err != nil {
+			if ctx.Err() != nil {
+				return succeeded, failed, nil
+			}
+			if rpcerr.PolicyOf(err) == rpcerr.PolicySkipAccount {
+				failed++
+				r.stream.failed(accountIndex, accountAddress, err)
+				log.Println("Account index: ", accountIndex, " Address: ", accountAddress.Hex(), " Balance is not enough to pay for gas fee and switch to next account")
+				return succeeded, failed, nil
+			}
+			failed++
+			r.stream.failed(accountIndex, accountAddress, err)
+			return succeeded, failed, err
+		}
+		succeeded++
+		r.stream.sent(accountIndex, accountAddress, result)
+		if err := r.journal.Sent(accountIndex, accountAddress, result.Tx); err != nil {
+			log.Println("Can not write journal", err)
+		}
+		r.tracker.Track(accountAddress, result.Tx, journaledBump(r.journal, sender, accountIndex))
+		log.Println("Account index: ", accountIndex, " Address: ", accountAddress.Hex(), " Nonce: ", result.Nonce, " Tx hash: ", result.Hash.Hex(), " Payload: ", string(r.payload))
+	}
+	return succeeded, failed, nil
 }
@@ -0,0 +1,203 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/indexer"
+	"cronos-tools/src/inscription"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"log"
+	"strings"
+	"time"
+)
+
+// errUnknownLim 索引服务不知道tick的lim，也没有指定--deploy-tx
+var errUnknownLim = errors.New("unknown lim")
+
+// txFetcher 按hash查询交易，用于读取deploy铭文
+type txFetcher interface {
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+}
+
+// mintCap 根据索引服务中tick的mint进度决定是否停止mint
+type mintCap struct {
+	indexer  indexer.Indexer
+	mint     inscription.Mint
+	stopAt   float64
+	interval time.Duration
+	// deployTx 不为空时从该deploy交易读取lim
+	deployTx        common.Hash
+	allowUnknownLim bool
+}
+
+// getMintCap 解析mint的payload，不是crc-20 mint铭文时返回nil
+func getMintCap(cmd *cobra.Command, payload []byte) (*mintCap, error) {
+	stopAt, err := cmd.Flags().GetFloat64("stop-at-progress")
+	if err != nil {
+		return nil, err
+	}
+	if stopAt <= 0 || stopAt > 100 {
+		return nil, errors.New("stop-at-progress must be in (0, 100]")
+	}
+	interval, err := cmd.Flags().GetDuration("progress-interval")
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return nil, errors.New("progress-interval must bigger than 0")
+	}
+	deployTx, err := cmd.Flags().GetString("deploy-tx")
+	if err != nil {
+		return nil, err
+	}
+	if deployTx != "" && len(common.FromHex(deployTx)) != common.HashLength {
+		return nil, fmt.Errorf("invalid deploy-tx %q", deployTx)
+	}
+	allowUnknownLim, err := cmd.Flags().GetBool("allow-unknown-lim")
+	if err != nil {
+		return nil, err
+	}
+	operation, err := inscription.Parse(payload)
+	if err != nil {
+		return nil, nil
+	}
+	mint, ok := operation.(inscription.Mint)
+	if !ok {
+		return nil, nil
+	}
+	idx, err := newIndexer(cmd)
+	if err != nil {
+		return nil, err
+	}
+	tickCap := &mintCap{indexer: idx, mint: mint, stopAt: stopAt, interval: interval, allowUnknownLim: allowUnknownLim}
+	if deployTx != "" {
+		tickCap.deployTx = common.HexToHash(deployTx)
+	}
+	return tickCap, nil
+}
+
+// check 查询tick当前进度，返回是否已经达到停止条件
+func (c *mintCap) check() (bool, error) {
+	tickInfo, err := c.indexer.TickInfo(context.Background(), c.mint.Tick)
+	if errors.Is(err, indexer.ErrNotFound) {
+		return false, errors.New("tick " + c.mint.Tick + " is not deployed")
+	}
+	if err != nil {
+		return false, err
+	}
+	log.Println("Tick:", tickInfo.Tick, "Minting progress:", tickInfo.Progress, "Minted:", tickInfo.MintedCount, "Total supply:", tickInfo.TotalSupply)
+	// MintedCount是mint交易数，TotalSupply是代币数量，只能用Progress判断
+	return tickInfo.Progress >= c.stopAt, nil
+}
+
+// tickLim 先从索引服务读取lim，索引服务不知道时从--deploy-tx的deploy铭文读取
+func (c *mintCap) tickLim(ctx context.Context, client txFetcher) (decimal.Decimal, error) {
+	if limIndexer, ok := c.indexer.(indexer.LimIndexer); ok {
+		lim, err := limIndexer.Lim(ctx, c.mint.Tick)
+		if err != nil && !errors.Is(err, indexer.ErrNotFound) {
+			return decimal.Zero, err
+		}
+		if err == nil && !lim.IsZero() {
+			return lim, nil
+		}
+	}
+	if c.deployTx == (common.Hash{}) {
+		return decimal.Zero, errUnknownLim
+	}
+	tx, _, err := client.TransactionByHash(ctx, c.deployTx)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("can not get deploy-tx %s: %w", c.deployTx.Hex(), err)
+	}
+	operation, err := inscription.Parse(tx.Data())
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("deploy-tx %s: %w", c.deployTx.Hex(), err)
+	}
+	deploy, ok := operation.(inscription.Deploy)
+	if !ok || !strings.EqualFold(deploy.Tick, c.mint.Tick) {
+		return decimal.Zero, fmt.Errorf("deploy-tx %s is not the deploy inscription of tick %s", c.deployTx.Hex(), c.mint.Tick)
+	}
+	return decimal.NewFromString(deploy.Lim)
+}
+
+// checkLim mint数量超过tick的lim时提示，超出部分的铭文是无效的。lim未知时除非指定
+// --allow-unknown-lim，否则不开始mint
+func (c *mintCap) checkLim(ctx context.Context, client txFetcher) error {
+	lim, err := c.tickLim(ctx, client)
+	if errors.Is(err, errUnknownLim) {
+		if c.allowUnknownLim {
+			log.Println("The lim of tick", c.mint.Tick, "is unknown, amt", c.mint.Amt, "is not checked against it")
+			return nil
+		}
+		return fmt.Errorf("the indexer does not know the lim of tick %s, set --deploy-tx to read it from the deploy inscription or --allow-unknown-lim to mint anyway", c.mint.Tick)
+	}
+	if err != nil {
+		return err
+	}
+	amt, err := decimal.NewFromString(c.mint.Amt)
+	if err != nil {
+		return err
+	}
+	if amt.GreaterThan(lim) {
+		log.Println("Warning: amt", c.mint.Amt, "is bigger than the lim", lim.String(), "of tick", c.mint.Tick)
+	}
+	return nil
+}
+
+// watch 定期查询进度，达到停止条件时调用cancel停止所有账户的mint
+func (c *mintCap) watch(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reached, err := c.check()
+			if err != nil {
+				log.Println("Can not get tick progress", err)
+				continue
+			}
+			if reached {
+				log.Println("Tick", c.mint.Tick, "reached", c.stopAt, "% minting progress, stop minting")
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// startMintCap 开始mint前检查进度并在后台监控。返回false表示已经不需要mint
+func startMintCap(ctx context.Context, cancel context.CancelFunc, cmd *cobra.Command, client txFetcher, payload []byte) bool {
+	tickCap, err := getMintCap(cmd, payload)
+	if err != nil {
+		log.Panicln(err)
+	}
+	if tickCap == nil {
+		return true
+	}
+	if err := tickCap.checkLim(ctx, client); err != nil {
+		log.Panicln(err)
+	}
+	reached, err := tickCap.check()
+	if err != nil {
+		log.Panicln("Error fetching tick progress:", err)
+	}
+	if reached {
+		log.Println("Tick", tickCap.mint.Tick, "already reached", tickCap.stopAt, "% minting progress, skip minting")
+		return false
+	}
+	go tickCap.watch(ctx, cancel)
+	return true
+}
+
+// addMintCapFlags 添加mint进度相关的参数
+func addMintCapFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64P("stop-at-progress", "", 100, "Stop minting when the minting progress of the tick reaches this percentage,default 100")
+	cmd.Flags().StringP("deploy-tx", "", "", "Hash of the deploy inscription of the tick, its lim is read through --rpc when the indexer does not know it")
+	cmd.Flags().BoolP("allow-unknown-lim", "", false, "Mint even when the lim of the tick is unknown")
+	cmd.Flags().DurationP("progress-interval", "", 30*time.Second, "Interval of checking the minting progress of the tick,default 30s")
+}
@@ -0,0 +1,127 @@
+package cobra
+
+import (
+	"bytes"
+	"context"
+	"cronos-tools/src/indexer"
+	"cronos-tools/src/inscription"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+	"log"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// captureLog 返回f运行期间的日志
+func captureLog(f func()) string {
+	var buf bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(previous)
+	f()
+	return buf.String()
+}
+
+// noLimIndexer 隐藏Memory的Lim，和croscribe一样不知道tick的lim
+type noLimIndexer struct {
+	indexer.Indexer
+}
+
+// fakeTxFetcher 按hash返回预先放入的交易
+type fakeTxFetcher map[common.Hash]*types.Transaction
+
+func (f fakeTxFetcher) TransactionByHash(_ context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	tx, ok := f[hash]
+	if !ok {
+		return nil, false, ethereum.NotFound
+	}
+	return tx, false, nil
+}
+
+// inscriptionTx 返回data为payload的交易
+func inscriptionTx(nonce uint64, payload string) *types.Transaction {
+	return types.NewTx(&types.LegacyTx{Nonce: nonce, Gas: 21000, GasPrice: big.NewInt(1), Data: []byte(payload)})
+}
+
+func TestMintCapCheckLim(t *testing.T) {
+	idx := indexer.NewMemory()
+	idx.SetTick(indexer.TickInfo{Tick: "cros"})
+	idx.SetLim("cros", decimal.NewFromInt(1000))
+
+	deploy := inscriptionTx(0, `data:,{"p":"crc-20","op":"deploy","tick":"moon","max":"21000000","lim":"500"}`)
+	mint := inscriptionTx(1, `data:,{"p":"crc-20","op":"mint","tick":"moon","amt":"500"}`)
+	client := fakeTxFetcher{deploy.Hash(): deploy, mint.Hash(): mint}
+
+	tests := []struct {
+		name     string
+		idx      indexer.Indexer
+		mint     inscription.Mint
+		deployTx common.Hash
+		allow    bool
+		wantLog  string
+		wantErr  string
+	}{
+		{name: "within lim", idx: idx, mint: inscription.Mint{Tick: "cros", Amt: "1000"}},
+		{name: "over lim", idx: idx, mint: inscription.Mint{Tick: "CROS", Amt: "1001"}, wantLog: "Warning: amt 1001 is bigger than the lim 1000"},
+		{name: "unknown lim", idx: noLimIndexer{idx}, mint: inscription.Mint{Tick: "cros", Amt: "1"}, wantErr: "--allow-unknown-lim"},
+		{name: "unknown tick", idx: idx, mint: inscription.Mint{Tick: "moon", Amt: "1"}, wantErr: "--deploy-tx"},
+		{name: "allow unknown lim", idx: noLimIndexer{idx}, mint: inscription.Mint{Tick: "cros", Amt: "1"}, allow: true, wantLog: "is unknown"},
+		{name: "lim from deploy tx", idx: noLimIndexer{idx}, mint: inscription.Mint{Tick: "MOON", Amt: "501"}, deployTx: deploy.Hash(), wantLog: "Warning: amt 501 is bigger than the lim 500"},
+		{name: "deploy tx within lim", idx: idx, mint: inscription.Mint{Tick: "moon", Amt: "500"}, deployTx: deploy.Hash()},
+		{name: "deploy tx of another tick", idx: idx, mint: inscription.Mint{Tick: "cros2", Amt: "1"}, deployTx: deploy.Hash(), wantErr: "is not the deploy inscription"},
+		{name: "not a deploy tx", idx: idx, mint: inscription.Mint{Tick: "moon", Amt: "1"}, deployTx: mint.Hash(), wantErr: "is not the deploy inscription"},
+		{name: "deploy tx not found", idx: idx, mint: inscription.Mint{Tick: "moon", Amt: "1"}, deployTx: common.HexToHash("0x01"), wantErr: "can not get deploy-tx"},
+	}
+	for _, test := range tests {
+		c := &mintCap{indexer: test.idx, mint: test.mint, deployTx: test.deployTx, allowUnknownLim: test.allow}
+		var err error
+		got := captureLog(func() { err = c.checkLim(context.Background(), client) })
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: checkLim = %v, want %q", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: checkLim = %v", test.name, err)
+		}
+		if test.wantLog == "" && got != "" {
+			t.Errorf("%s: checkLim logged %q, want nothing", test.name, got)
+		}
+		if !strings.Contains(got, test.wantLog) {
+			t.Errorf("%s: checkLim logged %q, want %q", test.name, got, test.wantLog)
+		}
+	}
+}
+
+func TestMintCapCheck(t *testing.T) {
+	idx := indexer.NewMemory()
+	c := &mintCap{indexer: idx, mint: inscription.Mint{Tick: "cros", Amt: "1"}, stopAt: 90}
+	if _, err := c.check(); err == nil || !strings.Contains(err.Error(), "not deployed") {
+		t.Errorf("check of a tick that is not deployed = %v, want not deployed", err)
+	}
+
+	tests := []struct {
+		tick indexer.TickInfo
+		want bool
+	}{
+		{indexer.TickInfo{Tick: "cros", Progress: 10}, false},
+		{indexer.TickInfo{Tick: "cros", Progress: 89.99}, false},
+		{indexer.TickInfo{Tick: "cros", Progress: 90}, true},
+		// MintedCount是mint交易数，和按代币数量计的TotalSupply不能比较
+		{indexer.TickInfo{Tick: "cros", Progress: 1, TotalSupply: 1000, MintedCount: 1000}, false},
+		{indexer.TickInfo{Tick: "cros", Progress: 95, TotalSupply: 1000, MintedCount: 1}, true},
+	}
+	for _, test := range tests {
+		idx.SetTick(test.tick)
+		var reached bool
+		var err error
+		captureLog(func() { reached, err = c.check() })
+		if err != nil || reached != test.want {
+			t.Errorf("check with %+v = %v %v, want %v", test.tick, reached, err, test.want)
+		}
+	}
+}
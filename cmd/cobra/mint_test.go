@@ -0,0 +1,79 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/journal"
+	"cronos-tools/src/rpcerr"
+	"cronos-tools/src/txengine"
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"testing"
+)
+
+func TestMintAccount(t *testing.T) {
+	keys, err := wallet.NewMnemonicSource(testMnemonic, "", utils.DefaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		sendErr       error
+		wantSucceeded uint
+		wantFailed    uint
+		wantErr       bool
+	}{
+		{name: "sent", wantSucceeded: 3},
+		// 余额不足时跳过账户，不返回错误
+		{name: "skip account", sendErr: errors.New("insufficient funds for gas * price + value"), wantFailed: 1},
+		// 其他错误返回给调用方，不在账户内panic
+		{name: "node error", sendErr: errors.New("execution reverted"), wantFailed: 1, wantErr: true},
+	}
+	for _, test := range tests {
+		key, err := keys.PrivateKey(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &fakeClient{}
+		sender := txengine.NewSender(client, big.NewInt(25), key)
+		if test.sendErr != nil {
+			client.sendErrTo = map[common.Address]error{sender.Address(): test.sendErr}
+		}
+		run := newTestMintRun(t, client, 3)
+		var succeeded, failed uint
+		captureLog(func() { succeeded, failed, err = run.mintAccount(context.Background(), sender, 0) })
+		if succeeded != test.wantSucceeded || failed != test.wantFailed {
+			t.Errorf("%s: mintAccount = %d succeeded, %d failed, want %d and %d", test.name, succeeded, failed, test.wantSucceeded, test.wantFailed)
+		}
+		if (err != nil) != test.wantErr || (err != nil && rpcerr.PolicyOf(err) != rpcerr.PolicyFail) {
+			t.Errorf("%s: mintAccount error = %v, want error %v", test.name, err, test.wantErr)
+		}
+		if run.tracker.Pending() != int(test.wantSucceeded) {
+			t.Errorf("%s: tracking %d transactions, want %d", test.name, run.tracker.Pending(), test.wantSucceeded)
+		}
+	}
+}
+
+func TestMintAccountResumed(t *testing.T) {
+	keys, err := wallet.NewMnemonicSource(testMnemonic, "", utils.DefaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := keys.PrivateKey(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeClient{}
+	sender := txengine.NewSender(client, big.NewInt(25), key)
+	run := newTestMintRun(t, client, 3)
+	// 上次任务已确认2笔
+	run.progress[0] = &journal.AccountProgress{Address: sender.Address(), Confirmed: 2, Pending: map[uint64]*types.Transaction{}, Unsent: map[uint64]*types.Transaction{}}
+	var succeeded uint
+	captureLog(func() { succeeded, _, err = run.mintAccount(context.Background(), sender, 0) })
+	if err != nil || succeeded != 1 || len(client.sent) != 1 {
+		t.Errorf("resumed mintAccount = %d succeeded %v, sent %d, want only the missing transaction sent", succeeded, err, len(client.sent))
+	}
+}
@@ -0,0 +1,109 @@
+package cobra
+
+import (
+	"cronos-tools/src/output"
+	"cronos-tools/src/txengine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// getOutput 根据全局--output参数创建标准输出的渲染器，日志仍然输出到标准错误
+func getOutput(cmd *cobra.Command) *output.Writer {
+	return getOutputTo(cmd, os.Stdout)
+}
+
+// getOutputTo 根据全局--output参数创建输出到w的渲染器
+func getOutputTo(cmd *cobra.Command, w io.Writer) *output.Writer {
+	value, err := cmd.Flags().GetString("output")
+	if err != nil {
+		log.Panicln(err)
+	}
+	format, err := output.ParseFormat(value)
+	if err != nil {
+		log.Panicln(err)
+	}
+	return output.NewWriter(w, format)
+}
+
+// writeOutput 输出一条记录，失败时退出
+func writeOutput(out *output.Writer, record interface{}) {
+	if err := out.Write(record); err != nil {
+		log.Panicln(err)
+	}
+}
+
+// txRecord mint和collect输出的每笔交易结果，发送时和有最终结果时各输出一条
+type txRecord struct {
+	AccountIndex uint           `json:"account_index"`
+	Address      common.Address `json:"address"`
+	Nonce        uint64         `json:"nonce"`
+	Hash         common.Hash    `json:"hash"`
+	Status       string         `json:"status"`
+	BlockNumber  uint64         `json:"block_number,omitempty"`
+	GasUsed      uint64         `json:"gas_used,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// txStream 把交易结果写到--output，可以被多个goroutine同时使用
+type txStream struct {
+	out     *output.Writer
+	mu      sync.Mutex
+	indexes map[common.Address]uint
+}
+
+// newTxStream mint和collect会运行很久，表格也逐行输出
+func newTxStream(cmd *cobra.Command) *txStream {
+	out := getOutput(cmd)
+	out.Stream = true
+	return &txStream{out: out, indexes: make(map[common.Address]uint)}
+}
+
+func (s *txStream) write(record txRecord) {
+	if err := s.out.Write(record); err != nil {
+		log.Println("Can not write output", err)
+	}
+}
+
+// account 记录地址对应的账户序号，用于输出恢复任务中继续跟踪的交易
+func (s *txStream) account(accountIndex uint, address common.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.indexes[address] = accountIndex
+}
+
+func (s *txStream) sent(accountIndex uint, address common.Address, result *txengine.Result) {
+	s.write(txRecord{AccountIndex: accountIndex, Address: address, Nonce: result.Nonce, Hash: result.Hash, Status: string(result.Status)})
+}
+
+func (s *txStream) failed(accountIndex uint, address common.Address, err error) {
+	s.write(txRecord{AccountIndex: accountIndex, Address: address, Status: string(txengine.StatusFailed), Error: err.Error()})
+}
+
+func (s *txStream) confirmed(confirmation *txengine.Confirmation) {
+	s.mu.Lock()
+	accountIndex := s.indexes[confirmation.Account]
+	s.mu.Unlock()
+	s.write(txRecord{
+		AccountIndex: accountIndex,
+		Address:      confirmation.Account,
+		Nonce:        confirmation.Nonce,
+		Hash:         confirmation.Hash,
+		Status:       string(confirmation.Status),
+		BlockNumber:  confirmation.BlockNumber,
+		GasUsed:      confirmation.GasUsed,
+	})
+}
+
+func (s *txStream) flush() {
+	if err := s.out.Flush(); err != nil {
+		log.Println("Can not write output", err)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringP("output", "", string(output.FormatTable), "Output format of results, table, json or csv. Logs are written to stderr")
+}
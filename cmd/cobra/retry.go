@@ -0,0 +1,41 @@
+package cobra
+
+import (
+	"cronos-tools/src/retry"
+	"github.com/spf13/cobra"
+)
+
+// getRetryPolicy 从--retry-*参数构造所有rpc和索引服务调用共用的重试策略
+func getRetryPolicy(cmd *cobra.Command) (retry.Policy, error) {
+	policy := retry.Default()
+	var err error
+	if policy.MaxAttempts, err = cmd.Flags().GetInt("retry-max-attempts"); err != nil {
+		return policy, err
+	}
+	if policy.InitialDelay, err = cmd.Flags().GetDuration("retry-initial-delay"); err != nil {
+		return policy, err
+	}
+	if policy.MaxDelay, err = cmd.Flags().GetDuration("retry-max-delay"); err != nil {
+		return policy, err
+	}
+	if policy.Multiplier, err = cmd.Flags().GetFloat64("retry-multiplier"); err != nil {
+		return policy, err
+	}
+	if policy.Jitter, err = cmd.Flags().GetFloat64("retry-jitter"); err != nil {
+		return policy, err
+	}
+	if policy.CallTimeout, err = cmd.Flags().GetDuration("retry-call-timeout"); err != nil {
+		return policy, err
+	}
+	return policy, policy.Validate()
+}
+
+func init() {
+	policy := retry.Default()
+	rootCmd.PersistentFlags().IntP("retry-max-attempts", "", policy.MaxAttempts, "Times a failed rpc or indexer call is tried in total,default 5")
+	rootCmd.PersistentFlags().DurationP("retry-initial-delay", "", policy.InitialDelay, "Wait before the first retry, doubled by --retry-multiplier after each retry,default 1s")
+	rootCmd.PersistentFlags().DurationP("retry-max-delay", "", policy.MaxDelay, "Longest wait between two retries,default 30s")
+	rootCmd.PersistentFlags().Float64P("retry-multiplier", "", policy.Multiplier, "Factor the wait grows by after each retry,default 2")
+	rootCmd.PersistentFlags().Float64P("retry-jitter", "", policy.Jitter, "Fraction of the wait randomly added or removed so workers do not retry together,default 0.2")
+	rootCmd.PersistentFlags().DurationP("retry-call-timeout", "", policy.CallTimeout, "Deadline of a single rpc call, indexer requests use --indexer-timeout,default 20s")
+}
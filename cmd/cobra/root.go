@@ -3,6 +3,7 @@ package cobra
 import (
 	"github.com/spf13/cobra"
 	"log"
+	"os"
 )
 
 // Path: cmd/cobra/root.go
@@ -16,7 +17,8 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() {
+	// cobra已经输出了错误信息
 	if err := rootCmd.Execute(); err != nil {
-		log.Panicln(err)
+		os.Exit(1)
 	}
 }
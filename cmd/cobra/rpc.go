@@ -0,0 +1,51 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/rpcpool"
+	"github.com/spf13/cobra"
+	"log"
+	"strings"
+	"time"
+)
+
+// dialRPC 连接--rpc中用逗号分隔的所有节点，调用会自动路由到健康的节点，并按--retry-*重试
+func dialRPC(cmd *cobra.Command, rpc string) (*rpcpool.Pool, error) {
+	policy, err := getRetryPolicy(cmd)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pool, err := rpcpool.Dial(ctx, strings.Split(rpc, ","))
+	if err != nil {
+		return nil, err
+	}
+	pool.Retry = policy
+	statuses := pool.Status()
+	if len(statuses) > 1 {
+		for _, status := range statuses {
+			if status.LastError != nil {
+				log.Println("Rpc:", status.URL, "Healthy:", status.Healthy, "Error:", status.LastError)
+				continue
+			}
+			log.Println("Rpc:", status.URL, "Healthy:", status.Healthy, "Height:", status.Height, "Latency:", status.Latency)
+		}
+	}
+	pool.Start(context.Background())
+	return pool, nil
+}
+
+// logBroadcastStats 打印广播时每个节点最先接受交易的次数
+func logBroadcastStats(pool *rpcpool.Pool) {
+	if !pool.FanOut {
+		return
+	}
+	statuses := pool.Status()
+	if len(statuses) < 2 {
+		return
+	}
+	for _, status := range statuses {
+		log.Println("Rpc:", status.URL, "First accepted:", status.FirstAccepts)
+	}
+}
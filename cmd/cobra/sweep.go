@@ -0,0 +1,159 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/txengine"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"log"
+	"math/big"
+	"strings"
+)
+
+var sweepCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "Send all native coin of bip-44 sequence addresses to one address",
+	Long: `Send all native coin of bip-44 sequence addresses to one address, the fee is taken from the swept amount so the balances end at zero.
+--gas-margin is not applied. When the recipient is a contract the gas limit is the node estimate, gas the call does not use is refunded and stays in the account as dust`,
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := getKeySource(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		rpc, err := cmd.Flags().GetString("rpc")
+		if err != nil {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		if rpc == "" {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		startIndex, err := cmd.Flags().GetUint("start-index")
+		if err != nil {
+			log.Panicln(errors.New("start-index is required"))
+		}
+		endIndex, err := cmd.Flags().GetUint("end-index")
+		if err != nil {
+			log.Panicln(errors.New("end-index is required"))
+		}
+		if startIndex > endIndex {
+			log.Panicln(errors.New("start-index must less than or equal to end-index"))
+		}
+		to, err := cmd.Flags().GetString("to")
+		if err != nil {
+			log.Panicln(errors.New("to is required"))
+		}
+		to = strings.TrimSpace(to)
+		if !common.IsHexAddress(to) {
+			log.Panicln(errors.New("to must be an address"))
+		}
+		toAddress := common.HexToAddress(to)
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			log.Panicln(err)
+		}
+		feeConfig, err := getFeeConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		gasConfig, err := getGasConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		if feeConfig.DynamicFee {
+			// EIP-1559交易实际费用取决于base fee，无法精确扣除
+			log.Panicln(errors.New("sweep only sends legacy transactions so the fee is exact"))
+		}
+
+		client, err := dialRPC(cmd, rpc)
+		if err != nil {
+			log.Panicln(err)
+		}
+		networkID, err := client.NetworkID(context.Background())
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		tracker := newTracker(context.Background(), client, nil, nil)
+		accountIndexes := make(map[common.Address]uint)
+		totalSwept := new(big.Int)
+		totalFee := new(big.Int)
+		swept := 0
+		for i := startIndex; i <= endIndex; i++ {
+			accountPrivateKey, err := keys.PrivateKey(i)
+			if err != nil {
+				log.Panicln(err)
+			}
+			sender := txengine.NewSender(client, networkID, accountPrivateKey)
+			sender.Retry = client.Retry
+			sender.Gas = gasConfig
+			// 余量会作为未用完的gas退回账户，留下余额
+			sender.Gas.Margin = 0
+			sender.Fees = feeConfig
+			accountAddress := sender.Address()
+			if accountAddress == toAddress {
+				continue
+			}
+			balance, err := client.BalanceAt(context.Background(), accountAddress, nil)
+			if err != nil {
+				log.Panicln(err)
+			}
+			// 价格高于--fee-ceiling时暂停，直到价格回落
+			fees, err := sender.WaitFees(context.Background())
+			if err != nil {
+				log.Panicln(err)
+			}
+			amount, fee, err := sweepAmount(context.Background(), sender, toAddress, balance, fees.GasPrice)
+			if err != nil {
+				log.Panicln(err)
+			}
+			if amount.Sign() <= 0 {
+				log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Balance:", formatCRO(balance), "CRO", "Not enough to pay for gas fee, skip")
+				continue
+			}
+			log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Balance:", formatCRO(balance), "CRO", "Sweep:", formatCRO(amount), "CRO", "Fee:", formatCRO(fee), "CRO")
+			totalSwept.Add(totalSwept, amount)
+			totalFee.Add(totalFee, fee)
+			swept++
+			if dryRun {
+				continue
+			}
+			result, err := sender.SendWithFees(context.Background(), toAddress, amount, nil, fees)
+			if err != nil {
+				log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Can not sweep", err)
+				continue
+			}
+			accountIndexes[accountAddress] = i
+			tracker.Track(accountAddress, result.Tx, nil)
+			log.Println("Account index:", i, "Address:", accountAddress.Hex(), "Tx hash:", result.Hash.Hex())
+		}
+		log.Println("Accounts swept:", swept, "Total swept:", formatCRO(totalSwept), "CRO", "Total fee:", formatCRO(totalFee), "CRO", "To:", toAddress.Hex())
+		if !dryRun {
+			waitConfirmations(cmd, tracker, accountIndexes)
+		}
+	},
+}
+
+// sweepAmount 估算转出全部余额的gas limit并设置到sender，返回转出金额和精确的手续费。
+// 转给普通地址时gas固定为21000，转给合约时使用节点按转账金额的估算
+func sweepAmount(ctx context.Context, sender *txengine.Sender, to common.Address, balance *big.Int, gasPrice *big.Int) (amount *big.Int, fee *big.Int, err error) {
+	// 合约的gas消耗可能和收到的金额有关，用余额代表转账金额估算
+	if sender.GasLimit, err = sender.EstimateGas(ctx, to, balance, nil); err != nil {
+		return nil, nil, err
+	}
+	fee = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(sender.GasLimit))
+	return new(big.Int).Sub(balance, fee), fee, nil
+}
+
+func init() {
+	rootCmd.AddCommand(sweepCmd)
+	addKeyFlags(sweepCmd)
+	sweepCmd.Flags().StringP("rpc", "r", "", "Specify the rpc url, several urls separated by commas fail over to each other")
+	sweepCmd.Flags().StringP("to", "", "", "Address receiving the swept native coin")
+	sweepCmd.Flags().UintP("start-index", "s", 0, "Start index of bip-44 sequence addresses,default 0")
+	sweepCmd.Flags().UintP("end-index", "e", 0, "End index of bip-44 sequence addresses,default 0")
+	sweepCmd.Flags().BoolP("dry-run", "", false, "Only print what would be swept")
+	addFeeFlags(sweepCmd)
+	addGasFlags(sweepCmd)
+	addConfirmFlags(sweepCmd)
+}
@@ -0,0 +1,46 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/txengine"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+func TestSweepAmount(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	balance := big.NewInt(10_000_000)
+	gasPrice := big.NewInt(100)
+	tests := []struct {
+		name     string
+		estimate uint64
+		wantGas  uint64
+	}{
+		{"plain address", 21000, 21000},
+		{"contract", 30000, 30000},
+	}
+	for _, test := range tests {
+		client := &fakeClient{estimate: test.estimate}
+		sender := txengine.NewSender(client, big.NewInt(25), key)
+		sender.Gas = txengine.DefaultGasConfig()
+		sender.Gas.Margin = 0
+		var amount, fee *big.Int
+		captureLog(func() { amount, fee, err = sweepAmount(context.Background(), sender, to, balance, gasPrice) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(client.calls) != 1 || client.calls[0].Value == nil || client.calls[0].Value.Cmp(balance) != 0 {
+			t.Errorf("%s: estimated with %+v, want the balance as value", test.name, client.calls)
+		}
+		wantFee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(test.wantGas))
+		if sender.GasLimit != test.wantGas || fee.Cmp(wantFee) != 0 || new(big.Int).Add(amount, fee).Cmp(balance) != 0 {
+			t.Errorf("%s: gas %d, amount %s, fee %s, want gas %d and fee %s", test.name, sender.GasLimit, amount, fee, test.wantGas, wantFee)
+		}
+	}
+}
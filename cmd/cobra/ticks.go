@@ -1,13 +1,14 @@
 package cobra
 
 import (
-	"encoding/json"
+	"context"
+	"cronos-tools/src/indexer"
+	"errors"
 	"fmt"
 	"github.com/spf13/cobra"
-	"io"
 	"log"
-	"net/http"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -17,133 +18,247 @@ var ticksCmd = &cobra.Command{
 
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Println("ticks called")
-		sortByDeployedTime, err := cmd.Flags().GetBool("sort-by-deployed-time")
+		filter, err := getTickFilter(cmd)
 		if err != nil {
 			log.Panicln(err)
 		}
-		sortByMintingProgress, err := cmd.Flags().GetBool("sort-by-minting-progress")
+		sortKeys, err := getTickSort(cmd)
 		if err != nil {
 			log.Panicln(err)
 		}
-		sortByHolders, err := cmd.Flags().GetBool("sort-by-holders")
+		pageSize, err := cmd.Flags().GetInt("page-size")
 		if err != nil {
 			log.Panicln(err)
 		}
+		if pageSize <= 0 {
+			log.Panicln(errors.New("page-size must bigger than 0"))
+		}
 
-		if sortByDeployedTime == false && sortByMintingProgress == false && sortByHolders == false {
-			sortByDeployedTime = true
+		idx, err := newIndexer(cmd)
+		if err != nil {
+			log.Panicln(err)
 		}
-		ticksInfo, err := getTicksInfo()
+		allTicks, err := indexer.AllTicks(context.Background(), idx, pageSize)
 		if err != nil {
 			log.Panicln("Error fetching ticks info:", err)
 		}
-		if len(ticksInfo.Content) == 0 {
+		var ticks []indexer.TickInfo
+		for _, tick := range allTicks {
+			if filter.match(tick) {
+				ticks = append(ticks, tick)
+			}
+		}
+		if len(ticks) == 0 {
 			log.Println("No ticks info")
 			return
 		}
 
-		if sortByDeployedTime {
-			log.Println("Sort by deployed time:")
-			sort.Slice(ticksInfo.Content, func(i, j int) bool {
-				return ticksInfo.Content[i].DeployTime.After(ticksInfo.Content[j].DeployTime)
-			})
-
-			for _, tick := range ticksInfo.Content {
-				log.Println("Tick:", tick.Tick, "Holder count:", tick.HolderCount, "Minting progress:", tick.Progress, "Deployed time:", tick.DeployTime.Format(time.RFC3339))
-			}
-			return
+		sortTicks(ticks, sortKeys)
+		log.Println("Sort by", sortKeys)
+		out := getOutput(cmd)
+		for _, tick := range ticks {
+			writeOutput(out, tick)
 		}
+		if err := out.Flush(); err != nil {
+			log.Panicln(err)
+		}
+	},
+}
 
-		if sortByMintingProgress {
-			log.Println("Sort by minting progress:")
-			sort.Slice(ticksInfo.Content, func(i, j int) bool {
-				return ticksInfo.Content[i].Progress > ticksInfo.Content[j].Progress
-			})
+// tickFilter ticks命令的过滤条件，零值表示不过滤
+type tickFilter struct {
+	protocol       string
+	name           string
+	minHolders     int
+	maxProgress    float64
+	hasMaxProgress bool
+	deployedAfter  time.Time
+}
 
-			for _, tick := range ticksInfo.Content {
-				log.Println("Tick:", tick.Tick, "Holder count:", tick.HolderCount, "Minting progress:", tick.Progress, "Deployed time:", tick.DeployTime.Format(time.RFC3339))
-			}
-			return
+func (f tickFilter) match(tick indexer.TickInfo) bool {
+	if f.protocol != "" && !strings.EqualFold(tick.Protocol, f.protocol) {
+		return false
+	}
+	if f.name != "" && !strings.Contains(strings.ToLower(tick.Tick), strings.ToLower(f.name)) {
+		return false
+	}
+	if tick.HolderCount < f.minHolders {
+		return false
+	}
+	if f.hasMaxProgress && tick.Progress > f.maxProgress {
+		return false
+	}
+	if !f.deployedAfter.IsZero() && !tick.DeployTime.After(f.deployedAfter) {
+		return false
+	}
+	return true
+}
+
+func getTickFilter(cmd *cobra.Command) (tickFilter, error) {
+	var filter tickFilter
+	var err error
+	if filter.protocol, err = cmd.Flags().GetString("protocol"); err != nil {
+		return filter, err
+	}
+	if filter.name, err = cmd.Flags().GetString("tick"); err != nil {
+		return filter, err
+	}
+	if filter.minHolders, err = cmd.Flags().GetInt("min-holders"); err != nil {
+		return filter, err
+	}
+	if filter.maxProgress, err = cmd.Flags().GetFloat64("max-progress"); err != nil {
+		return filter, err
+	}
+	filter.hasMaxProgress = cmd.Flags().Changed("max-progress")
+	deployedAfter, err := cmd.Flags().GetString("deployed-after")
+	if err != nil {
+		return filter, err
+	}
+	if deployedAfter != "" {
+		if filter.deployedAfter, err = parseDeployedAfter(deployedAfter); err != nil {
+			return filter, err
 		}
+	}
+	return filter, nil
+}
 
-		if sortByHolders {
-			log.Println("Sort by holders:")
-			sort.Slice(ticksInfo.Content, func(i, j int) bool {
-				return ticksInfo.Content[i].HolderCount > ticksInfo.Content[j].HolderCount
-			})
+// parseDeployedAfter 支持RFC3339时间、日期，或者相对现在的时长，如24h
+func parseDeployedAfter(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid deployed-after %q, use 2006-01-02, RFC3339 or a duration such as 24h", value)
+}
 
-			for _, tick := range ticksInfo.Content {
-				log.Println("Tick:", tick.Tick, "Holder count:", tick.HolderCount, "Minting progress:", tick.Progress, "Deployed time:", tick.DeployTime.Format(time.RFC3339))
-			}
-			return
-		}
-	},
+// tickSortKey 一个排序字段，desc为true时从大到小
+type tickSortKey struct {
+	field string
+	desc  bool
 }
 
-func init() {
-	rootCmd.AddCommand(ticksCmd)
-	ticksCmd.Flags().BoolP("sort-by-deployed-time", "", false, "Sort by deployed time, default is sort by deployed time")
-	ticksCmd.Flags().BoolP("sort-by-minting-progress", "", false, "Sort by minting progress, default is sort by deployed time")
-	ticksCmd.Flags().BoolP("sort-by-holders", "", false, "Sort by holders, default is sort by deployed time")
+func (k tickSortKey) String() string {
+	if k.desc {
+		return k.field + ":desc"
+	}
+	return k.field + ":asc"
 }
 
-func getTicksInfo() (*TicksInfo, error) {
-	url := "https://api.croscribe.com/v2/inscriptions?page=0&size=100000"
-	resp, err := http.Get(url)
-	if err != nil {
-		fmt.Println("Error fetching data:", err)
-		return nil, err
+func (k tickSortKey) compare(a indexer.TickInfo, b indexer.TickInfo) int {
+	c := 0
+	switch k.field {
+	case "deployed":
+		c = a.DeployTime.Compare(b.DeployTime)
+	case "progress":
+		c = compareOrdered(a.Progress, b.Progress)
+	case "holders":
+		c = compareOrdered(a.HolderCount, b.HolderCount)
+	case "supply":
+		c = compareOrdered(a.TotalSupply, b.TotalSupply)
+	case "minted":
+		c = compareOrdered(a.MintedCount, b.MintedCount)
+	case "tick":
+		c = strings.Compare(strings.ToLower(a.Tick), strings.ToLower(b.Tick))
 	}
-	defer resp.Body.Close()
+	if k.desc {
+		return -c
+	}
+	return c
+}
+
+func compareOrdered[T int | int64 | float64](a T, b T) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
 
-	body, err := io.ReadAll(resp.Body)
+// sortTicks 按keys依次比较，稳定排序
+func sortTicks(ticks []indexer.TickInfo, keys []tickSortKey) {
+	sort.SliceStable(ticks, func(i, j int) bool {
+		for _, key := range keys {
+			if c := key.compare(ticks[i], ticks[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+}
+
+var tickSortFields = []string{"deployed", "progress", "holders", "supply", "minted", "tick"}
+
+// getTickSort 解析--sort，旧的--sort-by-*参数作为简写继续支持
+func getTickSort(cmd *cobra.Command) ([]tickSortKey, error) {
+	value, err := cmd.Flags().GetString("sort")
 	if err != nil {
-		fmt.Println("Error reading response body:", err)
 		return nil, err
 	}
-	var ticksInfo TicksInfo
-	if err := json.Unmarshal(body, &ticksInfo); err != nil {
-		fmt.Println("Error parsing JSON:", err)
-		return nil, err
+	if !cmd.Flags().Changed("sort") {
+		for _, shorthand := range [][2]string{
+			{"sort-by-deployed-time", "deployed:desc"},
+			{"sort-by-minting-progress", "progress:desc"},
+			{"sort-by-holders", "holders:desc"},
+		} {
+			if set, _ := cmd.Flags().GetBool(shorthand[0]); set {
+				value = shorthand[1]
+				break
+			}
+		}
+	}
 
+	var keys []tickSortKey
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, order, _ := strings.Cut(part, ":")
+		key := tickSortKey{field: strings.ToLower(field)}
+		switch strings.ToLower(order) {
+		case "", "asc":
+		case "desc":
+			key.desc = true
+		default:
+			return nil, fmt.Errorf("invalid sort order %q, must be asc or desc", order)
+		}
+		valid := false
+		for _, f := range tickSortFields {
+			valid = valid || key.field == f
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid sort field %q, must be one of %s", field, strings.Join(tickSortFields, ", "))
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("sort is empty")
 	}
-	return &ticksInfo, nil
+	return keys, nil
+}
+
+func init() {
+	rootCmd.AddCommand(ticksCmd)
+	addTicksFlags(ticksCmd)
 }
 
-type TicksInfo struct {
-	Content []struct {
-		Id          int       `json:"id"`
-		Protocol    string    `json:"protocol"`
-		Tick        string    `json:"tick"`
-		DeployTime  time.Time `json:"deploy_time"`
-		Progress    float64   `json:"progress"`
-		HolderCount int       `json:"holder_count"`
-		TotalSupply int64     `json:"total_supply"`
-		MintedCount int64     `json:"minted_count"`
-	} `json:"content"`
-	Pageable struct {
-		PageNumber int `json:"page_number"`
-		PageSize   int `json:"page_size"`
-		Sort       struct {
-			Sorted   bool `json:"sorted"`
-			Empty    bool `json:"empty"`
-			Unsorted bool `json:"unsorted"`
-		} `json:"sort"`
-		Offset  int  `json:"offset"`
-		Paged   bool `json:"paged"`
-		Unpaged bool `json:"unpaged"`
-	} `json:"pageable"`
-	Last          bool `json:"last"`
-	TotalPages    int  `json:"total_pages"`
-	TotalElements int  `json:"total_elements"`
-	First         bool `json:"first"`
-	Size          int  `json:"size"`
-	Number        int  `json:"number"`
-	Sort          struct {
-		Sorted   bool `json:"sorted"`
-		Empty    bool `json:"empty"`
-		Unsorted bool `json:"unsorted"`
-	} `json:"sort"`
-	NumberOfElements int  `json:"number_of_elements"`
-	Empty            bool `json:"empty"`
+// addTicksFlags 注册ticks命令的参数，测试里用它构造独立的命令
+func addTicksFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("sort", "", "deployed:desc", "Comma separated sort keys with :asc or :desc, keys are deployed, progress, holders, supply, minted and tick. eg: progress:desc,holders:desc")
+	cmd.Flags().StringP("protocol", "", "", "Only show ticks of this protocol, eg: crc-20")
+	cmd.Flags().StringP("tick", "t", "", "Only show ticks whose name contains this text")
+	cmd.Flags().IntP("min-holders", "", 0, "Only show ticks with at least this many holders")
+	cmd.Flags().Float64P("max-progress", "", 100, "Only show ticks whose minting progress is at most this percentage")
+	cmd.Flags().StringP("deployed-after", "", "", "Only show ticks deployed after this time, 2006-01-02, RFC3339 or a duration such as 24h")
+	cmd.Flags().IntP("page-size", "", 1000, "Number of ticks fetched per indexer request")
+	cmd.Flags().BoolP("sort-by-deployed-time", "", false, "Same as --sort deployed:desc")
+	cmd.Flags().BoolP("sort-by-minting-progress", "", false, "Same as --sort progress:desc")
+	cmd.Flags().BoolP("sort-by-holders", "", false, "Same as --sort holders:desc")
 }
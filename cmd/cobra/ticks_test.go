@@ -0,0 +1,145 @@
+package cobra
+
+import (
+	"cronos-tools/src/indexer"
+	"fmt"
+	"github.com/spf13/cobra"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTicksTestCmd 返回解析了args的ticks参数，和全局的ticksCmd互不影响
+func newTicksTestCmd(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{Use: "ticks"}
+	addTicksFlags(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestGetTickSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{"default", nil, "[deployed:desc]", ""},
+		{"multi key", []string{"--sort", "progress:desc, Holders ,tick:ASC"}, "[progress:desc holders:asc tick:asc]", ""},
+		{"deployed shorthand", []string{"--sort-by-deployed-time"}, "[deployed:desc]", ""},
+		{"progress shorthand", []string{"--sort-by-minting-progress"}, "[progress:desc]", ""},
+		{"holders shorthand", []string{"--sort-by-holders"}, "[holders:desc]", ""},
+		{"progress shorthand before holders", []string{"--sort-by-holders", "--sort-by-minting-progress"}, "[progress:desc]", ""},
+		{"sort overrides shorthand", []string{"--sort-by-holders", "--sort", "minted:desc"}, "[minted:desc]", ""},
+		{"unknown field", []string{"--sort", "price"}, "", "invalid sort field"},
+		{"unknown order", []string{"--sort", "holders:up"}, "", "invalid sort order"},
+		{"empty", []string{"--sort", " , "}, "", "sort is empty"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys, err := getTickSort(newTicksTestCmd(t, test.args...))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("err = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(keys); got != test.want {
+				t.Errorf("keys = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSortTicks(t *testing.T) {
+	day := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	ticks := []indexer.TickInfo{
+		{Tick: "b", Progress: 50, HolderCount: 10, DeployTime: day},
+		{Tick: "A", Progress: 100, HolderCount: 5, DeployTime: day.Add(time.Hour)},
+		{Tick: "c", Progress: 50, HolderCount: 20, DeployTime: day.Add(2 * time.Hour)},
+		{Tick: "d", Progress: 50, HolderCount: 10, DeployTime: day.Add(3 * time.Hour)},
+	}
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"deployed:desc", []string{"d", "c", "A", "b"}},
+		{"tick", []string{"A", "b", "c", "d"}},
+		// 进度相同时按holders从大到小，再相同时保持原来的顺序
+		{"progress:asc,holders:desc", []string{"c", "b", "d", "A"}},
+		{"progress:desc,deployed:asc", []string{"A", "b", "c", "d"}},
+	}
+	for _, test := range tests {
+		keys, err := getTickSort(newTicksTestCmd(t, "--sort", test.sort))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorted := append([]indexer.TickInfo(nil), ticks...)
+		sortTicks(sorted, keys)
+		var got []string
+		for _, tick := range sorted {
+			got = append(got, tick.Tick)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("--sort %s = %v, want %v", test.sort, got, test.want)
+		}
+	}
+}
+
+func TestTickFilter(t *testing.T) {
+	day := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	ticks := []indexer.TickInfo{
+		{Protocol: "crc-20", Tick: "cros", Progress: 100, HolderCount: 500, DeployTime: day},
+		{Protocol: "crc-20", Tick: "Crosy", Progress: 30, HolderCount: 50, DeployTime: day.AddDate(0, 0, 2)},
+		{Protocol: "crc-721", Tick: "moon", Progress: 10, HolderCount: 5, DeployTime: day.AddDate(0, 0, 5)},
+	}
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"no filter", nil, []string{"cros", "Crosy", "moon"}},
+		{"protocol", []string{"--protocol", "CRC-20"}, []string{"cros", "Crosy"}},
+		{"name contains", []string{"--tick", "ROS"}, []string{"cros", "Crosy"}},
+		{"min holders", []string{"--min-holders", "50"}, []string{"cros", "Crosy"}},
+		{"max progress", []string{"--max-progress", "30"}, []string{"Crosy", "moon"}},
+		{"deployed after date", []string{"--deployed-after", "2023-12-02"}, []string{"Crosy", "moon"}},
+		{"deployed after rfc3339", []string{"--deployed-after", "2023-12-03T00:00:00Z"}, []string{"moon"}},
+		{"combined", []string{"--protocol", "crc-20", "--max-progress", "99", "--min-holders", "10"}, []string{"Crosy"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := getTickFilter(newTicksTestCmd(t, test.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tick := range ticks {
+				if filter.match(tick) {
+					got = append(got, tick.Tick)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("filtered = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := getTickFilter(newTicksTestCmd(t, "--deployed-after", "yesterday")); err == nil {
+		t.Error("invalid --deployed-after returned no error")
+	}
+	filter, err := getTickFilter(newTicksTestCmd(t, "--deployed-after", "24h"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since := time.Since(filter.deployedAfter); since < 23*time.Hour || since > 25*time.Hour {
+		t.Errorf("--deployed-after 24h = %v, want about a day ago", filter.deployedAfter)
+	}
+}
@@ -0,0 +1,265 @@
+package cobra
+
+import (
+	"context"
+	"cronos-tools/src/inscription"
+	"cronos-tools/src/rpcerr"
+	"cronos-tools/src/txengine"
+	"cronos-tools/src/utils"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// transferRow 一笔转账：从哪个序号的地址转给谁多少，line是csv中的行号
+type transferRow struct {
+	line      int
+	fromIndex uint
+	to        common.Address
+	amount    string
+}
+
+var transferCmd = &cobra.Command{
+	Use:   "transfer",
+	Short: "Transfer an amount of a crc-20 tick to one or many recipients",
+	Long:  `Transfer an amount of a crc-20 tick from a bip-44 sequence address to a recipient, or many transfers listed in a csv file of from-index,to-address,amount rows`,
+	// 部分转账发送失败时只输出错误并以非0状态退出
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := getKeySource(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		rpc, err := cmd.Flags().GetString("rpc")
+		if err != nil {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		if rpc == "" {
+			log.Panicln(errors.New("rpc is required"))
+		}
+		tick, err := cmd.Flags().GetString("tick")
+		if err != nil {
+			log.Panicln(errors.New("tick is required"))
+		}
+		tick = strings.TrimSpace(tick)
+		if err := inscription.ValidateTick(tick); err != nil {
+			log.Panicln(err)
+		}
+
+		rows, err := getTransferRows(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		if len(rows) == 0 {
+			log.Panicln(errors.New("no transfer"))
+		}
+
+		idx, err := newIndexer(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		// 按发送地址汇总转账数量，检查索引服务中的余额是否足够
+		totals := make(map[uint]decimal.Decimal)
+		for _, row := range rows {
+			amount, _ := decimal.NewFromString(row.amount)
+			totals[row.fromIndex] = totals[row.fromIndex].Add(amount)
+		}
+		for fromIndex, total := range totals {
+			accountPrivateKey, err := keys.PrivateKey(fromIndex)
+			if err != nil {
+				log.Panicln(err)
+			}
+			accountAddress := utils.GetAddressFromPrivateKey(accountPrivateKey)
+			ticksBalance, err := idx.Balances(context.Background(), accountAddress)
+			if err != nil {
+				log.Panicln("Error fetching inscription balance:", err)
+			}
+			balance := decimal.Zero
+			for _, tb := range ticksBalance {
+				if strings.EqualFold(tb.Tick, tick) {
+					balance = decimal.NewFromInt(int64(tb.Amount))
+				}
+			}
+			log.Println("Account index:", fromIndex, "Address:", accountAddress.Hex(), "Tick:", tick, "Balance:", balance.String(), "Transfer:", total.String())
+			if balance.LessThan(total) {
+				log.Panicln(fmt.Errorf("account index %d balance %s of tick %s is less than %s", fromIndex, balance.String(), tick, total.String()))
+			}
+		}
+
+		client, err := dialRPC(cmd, rpc)
+		if err != nil {
+			log.Panicln(err)
+		}
+		networkID, err := client.NetworkID(context.Background())
+		if err != nil {
+			log.Panicln(err)
+		}
+		nonceManager, err := getNonceManager(cmd, client)
+		if err != nil {
+			log.Panicln(err)
+		}
+		feeConfig, err := getFeeConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+		gasConfig, err := getGasConfig(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		tracker := newTracker(context.Background(), client, nil, nil)
+		accountIndexes := make(map[common.Address]uint)
+		senders := make(map[uint]*txengine.Sender)
+		// skipped 余额不足以支付gas fee的账户，剩下的转账不再发送
+		skipped := make(map[uint]error)
+		var failed []transferRow
+		for _, row := range rows {
+			if err, ok := skipped[row.fromIndex]; ok {
+				failed = append(failed, row)
+				log.Println("Line:", row.line, "Account index:", row.fromIndex, "To:", row.to.Hex(), "Amount:", row.amount, "Skipped:", err)
+				continue
+			}
+			sender, ok := senders[row.fromIndex]
+			if !ok {
+				accountPrivateKey, err := keys.PrivateKey(row.fromIndex)
+				if err != nil {
+					log.Panicln(err)
+				}
+				sender = txengine.NewSender(client, networkID, accountPrivateKey)
+				sender.Retry = client.Retry
+				sender.Gas = gasConfig
+				sender.Nonces = nonceManager
+				sender.Fees = feeConfig
+				senders[row.fromIndex] = sender
+				accountIndexes[sender.Address()] = row.fromIndex
+			}
+			payload, err := inscription.Transfer{Tick: tick, Amt: row.amount}.Encode()
+			if err != nil {
+				log.Panicln(err)
+			}
+			result, err := sender.Send(context.Background(), row.to, payload)
+			if err != nil {
+				failed = append(failed, row)
+				log.Println("Line:", row.line, "Account index:", row.fromIndex, "To:", row.to.Hex(), "Amount:", row.amount, "Can not send transaction:", err)
+				if rpcerr.PolicyOf(err) == rpcerr.PolicySkipAccount {
+					log.Println("Account index:", row.fromIndex, "Balance is not enough to pay for gas fee, skip its remaining transfers")
+					skipped[row.fromIndex] = err
+				}
+				continue
+			}
+			tracker.Track(sender.Address(), result.Tx, sender.Bump)
+			log.Println("Line:", row.line, "Account index:", row.fromIndex, "Address:", sender.Address().Hex(), "To:", row.to.Hex(), "Tick:", tick, "Amount:", row.amount, "Tx hash:", result.Hash.Hex())
+		}
+		// 已经发送的交易总是等待结果，失败的转账放在最后输出
+		waitConfirmations(cmd, tracker, accountIndexes)
+		for _, row := range failed {
+			log.Println("Failed line:", row.line, "Account index:", row.fromIndex, "To:", row.to.Hex(), "Amount:", row.amount)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("%d of %d transfers were not sent", len(failed), len(rows))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(transferCmd)
+	addKeyFlags(transferCmd)
+	transferCmd.Flags().StringP("rpc", "r", "", "Specify the rpc url, several urls separated by commas fail over to each other")
+	transferCmd.Flags().StringP("tick", "t", "", "Specify the tick")
+	transferCmd.Flags().StringP("amt", "", "", "Amount to transfer")
+	transferCmd.Flags().StringP("to", "", "", "Recipient address")
+	transferCmd.Flags().UintP("from-index", "i", 0, "Index of the bip-44 sequence address sending the tick,default 0")
+	transferCmd.Flags().StringP("csv", "", "", "CSV file of from-index,to-address,amount rows instead of --from-index, --to and --amt")
+	transferCmd.Flags().UintP("max-in-flight", "", 5, "Max number of unconfirmed transactions per address,default 5")
+	addFeeFlags(transferCmd)
+	addGasFlags(transferCmd)
+	addConfirmFlags(transferCmd)
+}
+
+// getTransferRows 从--csv文件或--from-index、--to、--amt读取转账列表
+func getTransferRows(cmd *cobra.Command) ([]transferRow, error) {
+	csvPath, err := cmd.Flags().GetString("csv")
+	if err != nil {
+		return nil, err
+	}
+	if csvPath != "" {
+		file, err := os.Open(csvPath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return readTransferRows(file)
+	}
+	fromIndex, err := cmd.Flags().GetUint("from-index")
+	if err != nil {
+		return nil, err
+	}
+	to, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return nil, err
+	}
+	amt, err := cmd.Flags().GetString("amt")
+	if err != nil {
+		return nil, err
+	}
+	row, err := parseTransferRow(strconv.FormatUint(uint64(fromIndex), 10), to, amt)
+	if err != nil {
+		return nil, err
+	}
+	row.line = 1
+	return []transferRow{row}, nil
+}
+
+// readTransferRows 读取from-index,to-address,amount格式的csv，第一行可以是表头
+func readTransferRows(r io.Reader) ([]transferRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	var rows []transferRow
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 {
+			if _, err := strconv.ParseUint(strings.TrimSpace(record[0]), 10, 32); err != nil {
+				// 表头
+				continue
+			}
+		}
+		row, err := parseTransferRow(record[0], record[1], record[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		row.line = line
+		rows = append(rows, row)
+	}
+}
+
+func parseTransferRow(fromIndex string, to string, amount string) (transferRow, error) {
+	index, err := strconv.ParseUint(strings.TrimSpace(fromIndex), 10, 32)
+	if err != nil {
+		return transferRow{}, fmt.Errorf("invalid from-index %q", fromIndex)
+	}
+	to = strings.TrimSpace(to)
+	if !common.IsHexAddress(to) {
+		return transferRow{}, fmt.Errorf("invalid to-address %q", to)
+	}
+	amount = strings.TrimSpace(amount)
+	if err := inscription.ValidateAmount("amt", amount); err != nil {
+		return transferRow{}, err
+	}
+	return transferRow{fromIndex: uint(index), to: common.HexToAddress(to), amount: amount}, nil
+}
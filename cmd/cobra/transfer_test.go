@@ -0,0 +1,30 @@
+package cobra
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"strings"
+	"testing"
+)
+
+func TestReadTransferRows(t *testing.T) {
+	rows, err := readTransferRows(strings.NewReader("from-index,to-address,amount\n0, 0x0000000000000000000000000000000000000001, 10\n2,0x0000000000000000000000000000000000000002,0.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []transferRow{
+		{line: 2, fromIndex: 0, to: common.HexToAddress("0x01"), amount: "10"},
+		{line: 3, fromIndex: 2, to: common.HexToAddress("0x02"), amount: "0.5"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %+v, want %+v", rows, want)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	if _, err := readTransferRows(strings.NewReader("0,0x01,10\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("invalid address error = %v, want it on line 1", err)
+	}
+}
@@ -0,0 +1,132 @@
+package cobra
+
+import (
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/spf13/cobra"
+	"github.com/tyler-smith/go-bip39"
+	"log"
+	"os"
+)
+
+var walletCmd = &cobra.Command{
+	Use:   "wallet",
+	Short: "Manage the encrypted local vault of the mnemonic and derived addresses",
+}
+
+var walletInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a vault with a new random mnemonic",
+	Run: func(cmd *cobra.Command, args []string) {
+		vaultPath, err := cmd.Flags().GetString("vault-path")
+		if err != nil {
+			log.Panicln(err)
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			log.Panicln(err)
+		}
+		entropy, err := bip39.NewEntropy(256)
+		if err != nil {
+			log.Panicln(err)
+		}
+		mnemonic, err := bip39.NewMnemonic(entropy)
+		if err != nil {
+			log.Panicln(err)
+		}
+		passphrase, err := readPassphrase("New vault passphrase: ", true)
+		if err != nil {
+			log.Panicln(err)
+		}
+		if err := wallet.SaveVault(vaultPath, mnemonic, passphrase, force); err != nil {
+			log.Panicln(err)
+		}
+		log.Println("Vault created:", vaultPath)
+		accountPrivateKey, err := utils.GetPrivateKey(mnemonic, 0)
+		if err != nil {
+			log.Panicln(err)
+		}
+		log.Println("Address of index 0:", utils.GetAddressFromPrivateKey(accountPrivateKey).Hex())
+		log.Println("Back up the mnemonic with: wallet export")
+	},
+}
+
+var walletImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Encrypt an existing mnemonic into the vault",
+	Run: func(cmd *cobra.Command, args []string) {
+		vaultPath, err := cmd.Flags().GetString("vault-path")
+		if err != nil {
+			log.Panicln(err)
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			log.Panicln(err)
+		}
+		mnemonicFile, err := cmd.Flags().GetString("mnemonic-file")
+		if err != nil {
+			log.Panicln(err)
+		}
+		var mnemonic string
+		if mnemonicFile != "" {
+			mnemonic, err = wallet.ReadMnemonicFile(mnemonicFile)
+		} else if mnemonic = os.Getenv(wallet.MnemonicEnv); mnemonic == "" {
+			mnemonic, err = prompt.Stdin.PromptPassword("Mnemonic: ")
+		}
+		if err != nil {
+			log.Panicln(err)
+		}
+		if mnemonic == "" {
+			log.Panicln(errors.New("mnemonic is required"))
+		}
+		passphrase, err := readPassphrase("New vault passphrase: ", true)
+		if err != nil {
+			log.Panicln(err)
+		}
+		if err := wallet.SaveVault(vaultPath, mnemonic, passphrase, force); err != nil {
+			log.Panicln(err)
+		}
+		log.Println("Vault created:", vaultPath)
+		accountPrivateKey, err := utils.GetPrivateKey(wallet.NormalizeMnemonic(mnemonic), 0)
+		if err != nil {
+			log.Panicln(err)
+		}
+		log.Println("Address of index 0:", utils.GetAddressFromPrivateKey(accountPrivateKey).Hex())
+	},
+}
+
+var walletExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Decrypt the vault and print the mnemonic",
+	Run: func(cmd *cobra.Command, args []string) {
+		vaultPath, err := cmd.Flags().GetString("vault-path")
+		if err != nil {
+			log.Panicln(err)
+		}
+		passphrase, err := readPassphrase("Vault passphrase: ", false)
+		if err != nil {
+			log.Panicln(err)
+		}
+		mnemonic, err := wallet.OpenVault(vaultPath, passphrase)
+		if err != nil {
+			log.Panicln(err)
+		}
+		fmt.Println(mnemonic)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(walletCmd)
+	walletCmd.AddCommand(walletInitCmd)
+	walletInitCmd.Flags().StringP("vault-path", "", wallet.DefaultVaultPath(), "Path of the encrypted local vault")
+	walletInitCmd.Flags().BoolP("force", "", false, "Overwrite an existing vault")
+	walletCmd.AddCommand(walletImportCmd)
+	walletImportCmd.Flags().StringP("vault-path", "", wallet.DefaultVaultPath(), "Path of the encrypted local vault")
+	walletImportCmd.Flags().StringP("mnemonic-file", "", "", "Read mnemonic from file, - for stdin, default prompt")
+	walletImportCmd.Flags().BoolP("force", "", false, "Overwrite an existing vault")
+	walletCmd.AddCommand(walletExportCmd)
+	walletExportCmd.Flags().StringP("vault-path", "", wallet.DefaultVaultPath(), "Path of the encrypted local vault")
+}
@@ -0,0 +1,146 @@
+package cobra
+
+import (
+	"cronos-tools/src/output"
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+)
+
+var walletDeriveCmd = &cobra.Command{
+	Use:   "derive",
+	Short: "List the addresses derived from the mnemonic, or check that an address belongs to it",
+	Run: func(cmd *cobra.Command, args []string) {
+		keys, err := getMnemonicSource(cmd)
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		verify, err := cmd.Flags().GetString("verify")
+		if err != nil {
+			log.Panicln(err)
+		}
+		if verify != "" {
+			if !common.IsHexAddress(verify) {
+				log.Panicln(errors.New("verify must be an address"))
+			}
+			search, err := cmd.Flags().GetUint("search")
+			if err != nil {
+				log.Panicln(err)
+			}
+			target := common.HexToAddress(verify)
+			accountIndex, err := findDerivedIndex(keys, target, search)
+			if err != nil {
+				log.Panicln(err)
+			}
+			log.Println("Address:", target.Hex(), "Account index:", accountIndex, "Path:", keys.Path(accountIndex))
+			return
+		}
+
+		startIndex, err := cmd.Flags().GetUint("start-index")
+		if err != nil {
+			log.Panicln(errors.New("start-index is required"))
+		}
+		endIndex, err := cmd.Flags().GetUint("end-index")
+		if err != nil {
+			log.Panicln(errors.New("end-index is required"))
+		}
+		if startIndex > endIndex {
+			log.Panicln(errors.New("start-index must less than or equal to end-index"))
+		}
+		reveal, err := cmd.Flags().GetBool("reveal")
+		if err != nil {
+			log.Panicln(err)
+		}
+		outPath, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Panicln(err)
+		}
+
+		var w io.Writer = os.Stdout
+		if outPath != "" {
+			file, err := os.OpenFile(outPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				log.Panicln(err)
+			}
+			defer file.Close()
+			w = file
+		}
+		out := getOutputTo(cmd, w)
+		if err := writeDerivedAccounts(out, keys, startIndex, endIndex, reveal); err != nil {
+			log.Panicln(err)
+		}
+	},
+}
+
+func init() {
+	walletCmd.AddCommand(walletDeriveCmd)
+	addMnemonicFlags(walletDeriveCmd)
+	walletDeriveCmd.Flags().UintP("start-index", "s", 0, "Start index of bip-44 sequence addresses,default 0")
+	walletDeriveCmd.Flags().UintP("end-index", "e", 0, "End index of bip-44 sequence addresses,default 0")
+	walletDeriveCmd.Flags().BoolP("reveal", "", false, "Also print the private keys")
+	walletDeriveCmd.Flags().StringP("out", "", "", "Write the result to a file instead of stdout, in the format of --output")
+	walletDeriveCmd.Flags().StringP("verify", "", "", "Check that the address is derived from the mnemonic")
+	walletDeriveCmd.Flags().UintP("search", "", 1000, "Number of indices to search when verifying an address,default 1000")
+}
+
+// findDerivedIndex 在前search个序号中查找派生出target的账户序号
+func findDerivedIndex(keys *wallet.MnemonicSource, target common.Address, search uint) (uint, error) {
+	for i := uint(0); i < search; i++ {
+		accountPrivateKey, err := keys.PrivateKey(i)
+		if err != nil {
+			return 0, err
+		}
+		if utils.GetAddressFromPrivateKey(accountPrivateKey) == target {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("address %s is not derived from the mnemonic in the first %d indices of %s", target.Hex(), search, keys.HDPath())
+}
+
+// writeDerivedAccounts 输出startIndex到endIndex的地址，reveal为true时同时输出私钥
+func writeDerivedAccounts(out *output.Writer, keys *wallet.MnemonicSource, startIndex uint, endIndex uint, reveal bool) error {
+	for i := startIndex; i <= endIndex; i++ {
+		accountPrivateKey, err := keys.PrivateKey(i)
+		if err != nil {
+			return err
+		}
+		address := utils.GetAddressFromPrivateKey(accountPrivateKey)
+		var record interface{} = derivedAccount{Index: i, Path: keys.Path(i), Address: address}
+		if reveal {
+			record = revealedAccount{
+				Index:      i,
+				Path:       keys.Path(i),
+				Address:    address,
+				PrivateKey: hex.EncodeToString(crypto.FromECDSA(accountPrivateKey)),
+			}
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// derivedAccount wallet derive输出的一行
+type derivedAccount struct {
+	Index   uint           `json:"index"`
+	Path    string         `json:"path"`
+	Address common.Address `json:"address"`
+}
+
+// revealedAccount 指定--reveal时输出的一行，包含私钥
+type revealedAccount struct {
+	Index      uint           `json:"index"`
+	Path       string         `json:"path"`
+	Address    common.Address `json:"address"`
+	PrivateKey string         `json:"private_key"`
+}
@@ -0,0 +1,98 @@
+package cobra
+
+import (
+	"bytes"
+	"cronos-tools/src/output"
+	"cronos-tools/src/utils"
+	"cronos-tools/src/wallet"
+	"encoding/json"
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hardhatMnemonic hardhat默认账户的助记词，BIP-44地址是公开的
+const hardhatMnemonic = "test test test test test test test test test test test junk"
+
+var hardhatAddresses = []common.Address{
+	common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
+	common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+	common.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"),
+}
+
+func TestFindDerivedIndex(t *testing.T) {
+	keys, err := wallet.NewMnemonicSource(hardhatMnemonic, "", utils.DefaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, address := range hardhatAddresses {
+		if index, err := findDerivedIndex(keys, address, 10); err != nil || index != uint(i) {
+			t.Errorf("findDerivedIndex(%s) = %d %v, want %d", address.Hex(), index, err, i)
+		}
+	}
+	// --search限制查找的序号数量
+	if _, err := findDerivedIndex(keys, hardhatAddresses[2], 2); err == nil || !strings.Contains(err.Error(), "first 2 indices") {
+		t.Errorf("findDerivedIndex beyond --search = %v, want not derived in the first 2 indices", err)
+	}
+	if _, err := findDerivedIndex(keys, common.HexToAddress("0x01"), 10); err == nil {
+		t.Error("findDerivedIndex of a foreign address returned no error")
+	}
+}
+
+func TestWriteDerivedAccounts(t *testing.T) {
+	keys, err := wallet.NewMnemonicSource(hardhatMnemonic, "", utils.DefaultHDPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeDerivedAccounts(output.NewWriter(&buf, output.FormatJSON), keys, 1, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines, want 2", len(lines))
+	}
+	var account revealedAccount
+	if err := json.Unmarshal([]byte(lines[0]), &account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Index != 1 || account.Path != "m/44'/60'/0'/0/1" || account.Address != hardhatAddresses[1] || account.PrivateKey != "59c6995e998f97a5a0044966f0945389dc9e86dae88c7a8412f4603b6b78690d" {
+		t.Errorf("first account = %+v, want hardhat account 1", account)
+	}
+}
+
+func TestWalletDeriveOut(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(config, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "accounts.csv")
+	rootCmd.SetArgs([]string{"wallet", "derive", "--config", config, "--mnemonic", hardhatMnemonic, "--end-index", "2", "--output", "csv", "--out", out})
+	defer rootCmd.SetArgs(nil)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("--out mode = %v, want 0600", info.Mode().Perm())
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("--out has %d lines, want a header and 3 accounts:\n%s", len(lines), content)
+	}
+	for i, address := range hardhatAddresses {
+		if !strings.Contains(lines[i+1], address.Hex()) {
+			t.Errorf("line %d = %q, want %s", i+1, lines[i+1], address.Hex())
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache keeps pages of ticks on disk for TTL so repeated ticks commands do not hit the API
+// every time. TickInfo of an indexer without a direct lookup walks the cached pages, so the
+// progress it returns may be up to TTL old. Balances and TxStatus are used to decide what to send
+// and always go to the wrapped indexer.
+type Cache struct {
+	next Indexer
+	dir  string
+	TTL  time.Duration
+}
+
+// DefaultCacheDir returns ~/.cronos-tools/cache.
+func DefaultCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".cronos-tools", "cache")
+	}
+	return filepath.Join(home, ".cronos-tools", "cache")
+}
+
+// NewCache wraps next, name tells apart the cached data of different indexers such as the api url.
+func NewCache(next Indexer, dir string, name string, ttl time.Duration) *Cache {
+	sum := sha256.Sum256([]byte(name))
+	return &Cache{next: next, dir: filepath.Join(dir, hex.EncodeToString(sum[:8])), TTL: ttl}
+}
+
+func (c *Cache) Balances(ctx context.Context, address common.Address) ([]Balance, error) {
+	return c.next.Balances(ctx, address)
+}
+
+func (c *Cache) Ticks(ctx context.Context, page int, size int) (*TicksPage, error) {
+	path := filepath.Join(c.dir, fmt.Sprintf("ticks-%d-%d.json", page, size))
+	var ticksPage TicksPage
+	if c.load(path, &ticksPage) {
+		return &ticksPage, nil
+	}
+	result, err := c.next.Ticks(ctx, page, size)
+	if err != nil {
+		return nil, err
+	}
+	c.store(path, result)
+	return result, nil
+}
+
+func (c *Cache) TickInfo(ctx context.Context, tick string) (*TickInfo, error) {
+	if !pagesTicks(c.next) {
+		return c.next.TickInfo(ctx, tick)
+	}
+	info, err := FindTick(ctx, c, tick, tickPageSize)
+	if errors.Is(err, ErrNotFound) {
+		// 缓存的列表里可能还没有刚deploy的tick
+		return c.next.TickInfo(ctx, tick)
+	}
+	return info, err
+}
+
+func (c *Cache) pagesTicks() bool {
+	return pagesTicks(c.next)
+}
+
+func (c *Cache) TxStatus(ctx context.Context, hash common.Hash) (*TxStatus, error) {
+	return c.next.TxStatus(ctx, hash)
+}
+
+// load 读取未过期的缓存，缓存不存在、过期或损坏时返回false
+func (c *Cache) load(path string, v interface{}) bool {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.TTL {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// store 写入缓存，写入失败只会导致下次重新请求，所以忽略错误
+func (c *Cache) store(path string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultCroscribeURL = "https://api.croscribe.com"
+
+// Croscribe reads from the croscribe HTTP API.
+type Croscribe struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewCroscribe(baseURL string, timeout time.Duration) *Croscribe {
+	if baseURL == "" {
+		baseURL = DefaultCroscribeURL
+	}
+	return &Croscribe{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Croscribe) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body of %s: %w", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPError{URL: u, StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error parsing JSON of %s: %w", u, err)
+	}
+	return nil
+}
+
+// Balances calls GET /balance/{address}.
+func (c *Croscribe) Balances(ctx context.Context, address common.Address) ([]Balance, error) {
+	var response struct {
+		Balances []Balance `json:"balances"`
+	}
+	if err := c.get(ctx, "/balance/"+address.Hex(), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Balances, nil
+}
+
+// Ticks calls GET /v2/inscriptions?page=&size=.
+func (c *Croscribe) Ticks(ctx context.Context, page int, size int) (*TicksPage, error) {
+	query := url.Values{}
+	query.Set("page", fmt.Sprint(page))
+	query.Set("size", fmt.Sprint(size))
+	var ticksPage TicksPage
+	if err := c.get(ctx, "/v2/inscriptions", query, &ticksPage); err != nil {
+		return nil, err
+	}
+	return &ticksPage, nil
+}
+
+// TickInfo pages through the tick list, the API has no lookup by tick.
+func (c *Croscribe) TickInfo(ctx context.Context, tick string) (*TickInfo, error) {
+	return FindTick(ctx, c, tick, tickPageSize)
+}
+
+func (c *Croscribe) pagesTicks() bool {
+	return true
+}
+
+// TxStatus is not available from the croscribe API.
+func (c *Croscribe) TxStatus(ctx context.Context, hash common.Hash) (*TxStatus, error) {
+	return nil, ErrUnsupported
+}
+
+// HTTPError is returned for responses other than 200 OK.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.URL, e.StatusCode, e.Body)
+}
@@ -0,0 +1,148 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupported 索引服务不支持该查询
+	ErrUnsupported = errors.New("not supported by this indexer")
+	ErrNotFound    = errors.New("not found")
+)
+
+// Indexer answers questions about CRC-20 state that can not be read from the chain directly.
+type Indexer interface {
+	// Balances returns every tick balance of address.
+	Balances(ctx context.Context, address common.Address) ([]Balance, error)
+	// Ticks returns one page of deployed ticks, pages start at 0.
+	Ticks(ctx context.Context, page int, size int) (*TicksPage, error)
+	// TickInfo returns the tick, matched case-insensitively, or ErrNotFound.
+	TickInfo(ctx context.Context, tick string) (*TickInfo, error)
+	// TxStatus returns how the indexer processed an inscription transaction, or ErrNotFound.
+	TxStatus(ctx context.Context, hash common.Hash) (*TxStatus, error)
+}
+
+// LimIndexer is implemented by indexers that know the per-mint limit of a tick. The croscribe
+// API does not return lim, so callers check for it with a type assertion.
+type LimIndexer interface {
+	// Lim returns the lim of the deploy inscription of tick, or ErrNotFound.
+	Lim(ctx context.Context, tick string) (decimal.Decimal, error)
+}
+
+type Balance struct {
+	TokenId  int    `json:"token_id"`
+	Chain    string `json:"chain"`
+	Protocol string `json:"protocol"`
+	Tick     string `json:"tick"`
+	Amount   int    `json:"amount"`
+}
+
+type TickInfo struct {
+	Id          int       `json:"id"`
+	Protocol    string    `json:"protocol"`
+	Tick        string    `json:"tick"`
+	DeployTime  time.Time `json:"deploy_time"`
+	Progress    float64   `json:"progress"`
+	HolderCount int       `json:"holder_count"`
+	// TotalSupply 部署时的max，按代币数量计
+	TotalSupply int64 `json:"total_supply"`
+	// MintedCount 有效mint铭文的笔数，不是已mint的代币数量
+	MintedCount int64 `json:"minted_count"`
+}
+
+type PageSort struct {
+	Sorted   bool `json:"sorted"`
+	Empty    bool `json:"empty"`
+	Unsorted bool `json:"unsorted"`
+}
+
+// TicksPage is a page of ticks in the layout of the croscribe API.
+type TicksPage struct {
+	Content  []TickInfo `json:"content"`
+	Pageable struct {
+		PageNumber int      `json:"page_number"`
+		PageSize   int      `json:"page_size"`
+		Sort       PageSort `json:"sort"`
+		Offset     int      `json:"offset"`
+		Paged      bool     `json:"paged"`
+		Unpaged    bool     `json:"unpaged"`
+	} `json:"pageable"`
+	Last             bool     `json:"last"`
+	TotalPages       int      `json:"total_pages"`
+	TotalElements    int      `json:"total_elements"`
+	First            bool     `json:"first"`
+	Size             int      `json:"size"`
+	Number           int      `json:"number"`
+	Sort             PageSort `json:"sort"`
+	NumberOfElements int      `json:"number_of_elements"`
+	Empty            bool     `json:"empty"`
+}
+
+// TxStatus is the result of indexing one inscription transaction.
+type TxStatus struct {
+	Hash  common.Hash `json:"hash"`
+	Valid bool        `json:"valid"`
+	Op    string      `json:"op"`
+	Tick  string      `json:"tick"`
+	Amt   string      `json:"amt"`
+	// Reason 无效铭文的原因
+	Reason string `json:"reason,omitempty"`
+}
+
+// tickPageSize 按页查找tick时每页的数量
+const tickPageSize = 1000
+
+// tickPager is implemented by indexers whose TickInfo only walks the pages of Ticks. Wrappers
+// check for it and walk the pages themselves, so every page goes through their limiter or cache.
+type tickPager interface {
+	pagesTicks() bool
+}
+
+// pagesTicks 判断idx的TickInfo是否只是遍历Ticks
+func pagesTicks(idx Indexer) bool {
+	pager, ok := idx.(tickPager)
+	return ok && pager.pagesTicks()
+}
+
+// FindTick walks the pages of idx until it finds tick. Indexers without a direct lookup use it for TickInfo.
+func FindTick(ctx context.Context, idx Indexer, tick string, pageSize int) (*TickInfo, error) {
+	for page := 0; ; page++ {
+		ticksPage, err := idx.Ticks(ctx, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for i := range ticksPage.Content {
+			if equalTick(ticksPage.Content[i].Tick, tick) {
+				return &ticksPage.Content[i], nil
+			}
+		}
+		if ticksPage.Last || len(ticksPage.Content) == 0 || page+1 >= ticksPage.TotalPages {
+			return nil, ErrNotFound
+		}
+	}
+}
+
+// AllTicks reads every page of ticks, following Last and TotalPages.
+func AllTicks(ctx context.Context, idx Indexer, pageSize int) ([]TickInfo, error) {
+	var ticks []TickInfo
+	for page := 0; ; page++ {
+		ticksPage, err := idx.Ticks(ctx, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		ticks = append(ticks, ticksPage.Content...)
+		if ticksPage.Last || len(ticksPage.Content) == 0 || page+1 >= ticksPage.TotalPages {
+			return ticks, nil
+		}
+	}
+}
+
+// equalTick tick不区分大小写
+func equalTick(a string, b string) bool {
+	return strings.EqualFold(a, b)
+}
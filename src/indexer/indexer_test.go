@@ -0,0 +1,222 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newMemoryTicks(names ...string) *Memory {
+	m := NewMemory()
+	for i, name := range names {
+		m.SetTick(TickInfo{Id: i + 1, Protocol: "crc-20", Tick: name})
+	}
+	return m
+}
+
+func TestFindTick(t *testing.T) {
+	m := newMemoryTicks("cros", "mint", "bull", "bear", "moon")
+	for _, pageSize := range []int{1, 2, 5, 10} {
+		info, err := FindTick(context.Background(), m, "MOON", pageSize)
+		if err != nil || info.Tick != "moon" {
+			t.Errorf("FindTick(MOON, page size %d) = %+v %v, want moon", pageSize, info, err)
+		}
+		if _, err := FindTick(context.Background(), m, "none", pageSize); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindTick(none, page size %d) = %v, want ErrNotFound", pageSize, err)
+		}
+	}
+	if _, err := FindTick(context.Background(), NewMemory(), "cros", 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindTick on an empty indexer = %v, want ErrNotFound", err)
+	}
+}
+
+func TestAllTicks(t *testing.T) {
+	m := newMemoryTicks("cros", "mint", "bull", "bear", "moon")
+	for _, pageSize := range []int{1, 2, 3, 5, 10} {
+		ticks, err := AllTicks(context.Background(), m, pageSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ticks) != 5 || ticks[0].Tick != "cros" || ticks[4].Tick != "moon" {
+			t.Errorf("AllTicks(page size %d) = %+v, want the 5 ticks in order", pageSize, ticks)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := newMemoryTicks("cros")
+	m.SetTick(TickInfo{Id: 1, Protocol: "crc-20", Tick: "CROS", Progress: 50})
+	info, err := m.TickInfo(context.Background(), "cros")
+	if err != nil || info.Progress != 50 {
+		t.Errorf("TickInfo after replacing the tick = %+v %v, want progress 50", info, err)
+	}
+
+	address := common.HexToAddress("0x01")
+	m.SetBalance(address, "cros", 10)
+	m.SetBalance(address, "CROS", 20)
+	balances, err := m.Balances(context.Background(), address)
+	if err != nil || len(balances) != 1 || balances[0].Amount != 20 {
+		t.Errorf("Balances = %+v %v, want one balance of 20", balances, err)
+	}
+
+	hash := common.HexToHash("0x02")
+	if _, err := m.TxStatus(context.Background(), hash); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TxStatus of an unknown tx = %v, want ErrNotFound", err)
+	}
+	m.SetTxStatus(TxStatus{Hash: hash, Valid: true, Op: "mint", Tick: "cros"})
+	if status, err := m.TxStatus(context.Background(), hash); err != nil || !status.Valid {
+		t.Errorf("TxStatus = %+v %v, want a valid mint", status, err)
+	}
+}
+
+// pagedIndexer 和croscribe一样只能按页查找tick，记录每页的请求次数，fail中的页第一次请求返回临时错误
+type pagedIndexer struct {
+	*Memory
+	mu    sync.Mutex
+	calls map[int]int
+	fail  map[int]bool
+}
+
+func newPagedIndexer(count int) *pagedIndexer {
+	m := NewMemory()
+	for i := 0; i < count; i++ {
+		m.ticks = append(m.ticks, TickInfo{Id: i + 1, Protocol: "crc-20", Tick: fmt.Sprintf("t%d", i)})
+	}
+	return &pagedIndexer{Memory: m, calls: map[int]int{}, fail: map[int]bool{}}
+}
+
+func (p *pagedIndexer) Ticks(ctx context.Context, page int, size int) (*TicksPage, error) {
+	p.mu.Lock()
+	p.calls[page]++
+	fail := p.fail[page] && p.calls[page] == 1
+	p.mu.Unlock()
+	if fail {
+		return nil, &HTTPError{StatusCode: http.StatusServiceUnavailable}
+	}
+	return p.Memory.Ticks(ctx, page, size)
+}
+
+func (p *pagedIndexer) TickInfo(ctx context.Context, tick string) (*TickInfo, error) {
+	return FindTick(ctx, p, tick, tickPageSize)
+}
+
+func (p *pagedIndexer) pagesTicks() bool {
+	return true
+}
+
+// total 返回所有页的请求次数
+func (p *pagedIndexer) total() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	total := 0
+	for _, calls := range p.calls {
+		total += calls
+	}
+	return total
+}
+
+func TestLimitedTickInfoRetriesOnePage(t *testing.T) {
+	paged := newPagedIndexer(2*tickPageSize + 1)
+	paged.fail[1] = true
+	limited := NewLimited(paged, 0, 1)
+	limited.Retry.InitialDelay = time.Millisecond
+
+	info, err := limited.TickInfo(context.Background(), "T2000")
+	if err != nil || info.Tick != "t2000" {
+		t.Fatalf("TickInfo = %+v %v, want t2000", info, err)
+	}
+	// 第1页失败后只重试第1页
+	if paged.calls[0] != 1 || paged.calls[1] != 2 || paged.calls[2] != 1 {
+		t.Errorf("page requests = %v, want page 1 retried alone", paged.calls)
+	}
+}
+
+func TestCacheTickInfoReadsCachedPages(t *testing.T) {
+	paged := newPagedIndexer(tickPageSize + 1)
+	cache := NewCache(NewLimited(paged, 0, 1), t.TempDir(), "test", time.Minute)
+
+	for i := 0; i < 3; i++ {
+		info, err := cache.TickInfo(context.Background(), "t1000")
+		if err != nil || info.Tick != "t1000" {
+			t.Fatalf("TickInfo = %+v %v, want t1000", info, err)
+		}
+	}
+	if total := paged.total(); total != 2 {
+		t.Errorf("indexer got %d page requests, want the 2 pages once", total)
+	}
+
+	// 缓存的列表里没有的tick重新请求
+	paged.SetTick(TickInfo{Id: tickPageSize + 2, Protocol: "crc-20", Tick: "new"})
+	if info, err := cache.TickInfo(context.Background(), "new"); err != nil || info.Tick != "new" {
+		t.Errorf("TickInfo of a tick deployed after caching = %+v %v, want new", info, err)
+	}
+	if _, err := cache.TickInfo(context.Background(), "none"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TickInfo of an unknown tick = %v, want ErrNotFound", err)
+	}
+}
+
+func TestWrappersKeepDirectLookups(t *testing.T) {
+	m := newMemoryTicks("cros")
+	cache := NewCache(NewLimited(m, 0, 1), t.TempDir(), "test", time.Minute)
+	if pagesTicks(cache) {
+		t.Error("a cache over an indexer with a direct lookup should not walk pages")
+	}
+	if info, err := cache.TickInfo(context.Background(), "cros"); err != nil || info.Tick != "cros" {
+		t.Errorf("TickInfo = %+v %v, want cros", info, err)
+	}
+}
+
+func TestLimitedTokenBucket(t *testing.T) {
+	address := common.HexToAddress("0x01")
+	// 每秒20次，一次最多2次：前2次立即完成，之后每次等50ms
+	limited := NewLimited(NewMemory(), 20, 2)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := limited.Balances(context.Background(), address); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("burst of 2 took %s, want no wait", elapsed)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := limited.Balances(context.Background(), address); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("6 requests took %s, want at least 200ms at 20 per second", elapsed)
+	}
+
+	// 等不到令牌时返回ctx的错误，不重试
+	slow := NewLimited(NewMemory(), 0.1, 1)
+	if _, err := slow.Balances(context.Background(), address); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := slow.Balances(ctx, address); err == nil {
+		t.Error("Balances without a token before the deadline returned no error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Balances waited %s, want it to give up at the deadline", elapsed)
+	}
+
+	// perSecond为0时不限速
+	unlimited := NewLimited(NewMemory(), 0, 0)
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		if _, err := unlimited.Balances(context.Background(), address); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("100 unlimited requests took %s, want no wait", elapsed)
+	}
+}
@@ -0,0 +1,106 @@
+package indexer
+
+import (
+	"context"
+	"cronos-tools/src/retry"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/time/rate"
+	"io"
+	"net"
+	"net/http"
+)
+
+// Limited spaces out requests to an indexer with a token bucket and retries transient errors
+// as Retry says. It is safe for concurrent use.
+type Limited struct {
+	next    Indexer
+	limiter *rate.Limiter
+	// Retry 临时错误的重试策略，Retryable固定为IsTransient，不使用CallTimeout
+	Retry retry.Policy
+}
+
+// NewLimited allows perSecond requests per second on average and burst requests at once.
+// perSecond 0 means no limit.
+func NewLimited(next Indexer, perSecond float64, burst int) *Limited {
+	limit := rate.Limit(perSecond)
+	if perSecond <= 0 {
+		limit = rate.Inf
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limited{next: next, limiter: rate.NewLimiter(limit, burst), Retry: retry.Default()}
+}
+
+func (l *Limited) Balances(ctx context.Context, address common.Address) ([]Balance, error) {
+	var balances []Balance
+	err := l.call(ctx, func(ctx context.Context) (err error) {
+		balances, err = l.next.Balances(ctx, address)
+		return err
+	})
+	return balances, err
+}
+
+func (l *Limited) Ticks(ctx context.Context, page int, size int) (*TicksPage, error) {
+	var ticksPage *TicksPage
+	err := l.call(ctx, func(ctx context.Context) (err error) {
+		ticksPage, err = l.next.Ticks(ctx, page, size)
+		return err
+	})
+	return ticksPage, err
+}
+
+// TickInfo walks the pages through Ticks when next has no direct lookup, so every page waits for
+// a token and a failed page is retried on its own.
+func (l *Limited) TickInfo(ctx context.Context, tick string) (*TickInfo, error) {
+	if pagesTicks(l.next) {
+		return FindTick(ctx, l, tick, tickPageSize)
+	}
+	var info *TickInfo
+	err := l.call(ctx, func(ctx context.Context) (err error) {
+		info, err = l.next.TickInfo(ctx, tick)
+		return err
+	})
+	return info, err
+}
+
+func (l *Limited) pagesTicks() bool {
+	return pagesTicks(l.next)
+}
+
+func (l *Limited) TxStatus(ctx context.Context, hash common.Hash) (*TxStatus, error) {
+	var status *TxStatus
+	err := l.call(ctx, func(ctx context.Context) (err error) {
+		status, err = l.next.TxStatus(ctx, hash)
+		return err
+	})
+	return status, err
+}
+
+func (l *Limited) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	policy := l.Retry
+	policy.Retryable = IsTransient
+	// 请求的超时由索引服务自己的timeout控制
+	policy.CallTimeout = 0
+	return policy.Do(ctx, "request indexer", func(ctx context.Context) error {
+		if err := l.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		return fn(ctx)
+	})
+}
+
+// IsTransient reports whether err is worth retrying: rate limiting, server errors and
+// network failures.
+func IsTransient(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
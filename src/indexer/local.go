@@ -0,0 +1,256 @@
+package indexer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 数据库中的key前缀
+const (
+	heightKey     = "meta/height"
+	tickCountKey  = "meta/ticks"
+	tickPrefix    = "tick/"
+	balancePrefix = "balance/"
+	txPrefix      = "tx/"
+)
+
+// Local is an Indexer backed by a leveldb database that is filled by Scanner.
+type Local struct {
+	db *leveldb.DB
+}
+
+// localTick 数据库中保存的tick状态
+type localTick struct {
+	TickInfo
+	Max decimal.Decimal `json:"max"`
+	// Lim 每次mint的上限，croscribe接口不返回，只有本地索引有
+	Lim         decimal.Decimal `json:"lim"`
+	Minted      decimal.Decimal `json:"minted"`
+	DeployTx    common.Hash     `json:"deploy_tx"`
+	DeployBlock uint64          `json:"deploy_block"`
+}
+
+// localBalance 数据库中保存的余额，保留小数部分
+type localBalance struct {
+	Tick   string          `json:"tick"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// DefaultLocalPath returns ~/.cronos-tools/index.
+func DefaultLocalPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".cronos-tools", "index")
+	}
+	return filepath.Join(home, ".cronos-tools", "index")
+}
+
+// OpenLocal opens or creates the index database at path. Only one process can open it at a time.
+func OpenLocal(path string) (*Local, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Local{db: db}, nil
+}
+
+func (l *Local) Close() error {
+	return l.db.Close()
+}
+
+// Height returns the last indexed block, ok is false when nothing has been indexed yet.
+func (l *Local) Height() (uint64, bool, error) {
+	value, err := l.db.Get([]byte(heightKey), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return binary.BigEndian.Uint64(value), true, nil
+}
+
+func (l *Local) Balances(ctx context.Context, address common.Address) ([]Balance, error) {
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(balanceKey(address, ""))), nil)
+	defer iter.Release()
+	var balances []Balance
+	for iter.Next() {
+		var balance localBalance
+		if err := json.Unmarshal(iter.Value(), &balance); err != nil {
+			return nil, err
+		}
+		if balance.Amount.Sign() <= 0 {
+			continue
+		}
+		balances = append(balances, Balance{
+			Chain:    "cronos",
+			Protocol: "crc-20",
+			Tick:     balance.Tick,
+			Amount:   int(balance.Amount.IntPart()),
+		})
+	}
+	return balances, iter.Error()
+}
+
+// Ticks returns the ticks in deploy order.
+func (l *Local) Ticks(ctx context.Context, page int, size int) (*TicksPage, error) {
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(tickPrefix)), nil)
+	defer iter.Release()
+	var ticks []TickInfo
+	for iter.Next() {
+		var tick localTick
+		if err := json.Unmarshal(iter.Value(), &tick); err != nil {
+			return nil, err
+		}
+		ticks = append(ticks, tick.TickInfo)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	sort.Slice(ticks, func(i, j int) bool {
+		return ticks[i].Id < ticks[j].Id
+	})
+	return pageOf(ticks, page, size), nil
+}
+
+func (l *Local) TickInfo(ctx context.Context, tick string) (*TickInfo, error) {
+	var info localTick
+	ok, err := l.get(tickKey(tick), &info)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &info.TickInfo, nil
+}
+
+func (l *Local) Lim(ctx context.Context, tick string) (decimal.Decimal, error) {
+	var info localTick
+	ok, err := l.get(tickKey(tick), &info)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !ok {
+		return decimal.Zero, ErrNotFound
+	}
+	return info.Lim, nil
+}
+
+func (l *Local) TxStatus(ctx context.Context, hash common.Hash) (*TxStatus, error) {
+	var status TxStatus
+	ok, err := l.get(txKey(hash), &status)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &status, nil
+}
+
+// pageOf 按croscribe接口的分页格式返回ticks的第page页
+func pageOf(ticks []TickInfo, page int, size int) *TicksPage {
+	if size <= 0 {
+		size = len(ticks)
+	}
+	ticksPage := &TicksPage{Size: size, Number: page, TotalElements: len(ticks)}
+	if size > 0 {
+		ticksPage.TotalPages = (len(ticks) + size - 1) / size
+	}
+	start := page * size
+	if start > len(ticks) {
+		start = len(ticks)
+	}
+	end := start + size
+	if end > len(ticks) {
+		end = len(ticks)
+	}
+	ticksPage.Content = append([]TickInfo(nil), ticks[start:end]...)
+	ticksPage.NumberOfElements = len(ticksPage.Content)
+	ticksPage.First = page == 0
+	ticksPage.Last = end >= len(ticks)
+	ticksPage.Empty = len(ticksPage.Content) == 0
+	ticksPage.Pageable.PageNumber = page
+	ticksPage.Pageable.PageSize = size
+	ticksPage.Pageable.Offset = start
+	ticksPage.Pageable.Paged = true
+	return ticksPage
+}
+
+func (l *Local) get(key string, v interface{}) (bool, error) {
+	value, err := l.db.Get([]byte(key), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(value, v)
+}
+
+// blockWriter 缓存一个区块内的修改，区块处理完后一次性写入数据库，
+// 同一区块中后面的交易可以读到前面交易的修改
+type blockWriter struct {
+	local   *Local
+	batch   *leveldb.Batch
+	pending map[string][]byte
+}
+
+func (l *Local) newBlockWriter() *blockWriter {
+	return &blockWriter{local: l, batch: new(leveldb.Batch), pending: make(map[string][]byte)}
+}
+
+func (w *blockWriter) get(key string, v interface{}) (bool, error) {
+	if value, ok := w.pending[key]; ok {
+		return true, json.Unmarshal(value, v)
+	}
+	return w.local.get(key, v)
+}
+
+func (w *blockWriter) put(key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.pending[key] = value
+	w.batch.Put([]byte(key), value)
+	return nil
+}
+
+// commit 写入区块内的所有修改并更新已索引高度
+func (w *blockWriter) commit(height uint64) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, height)
+	w.batch.Put([]byte(heightKey), value)
+	return w.local.db.Write(w.batch, nil)
+}
+
+func tickKey(tick string) string {
+	return tickPrefix + strings.ToLower(tick)
+}
+
+func balanceKey(address common.Address, tick string) string {
+	key := balancePrefix + strings.ToLower(address.Hex()) + "/"
+	if tick != "" {
+		key += strings.ToLower(tick)
+	}
+	return key
+}
+
+func txKey(hash common.Hash) string {
+	return txPrefix + hash.Hex()
+}
@@ -0,0 +1,112 @@
+package indexer
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"strings"
+	"sync"
+)
+
+// Memory is an in-memory Indexer for tests and local stubs.
+type Memory struct {
+	mu       sync.RWMutex
+	balances map[common.Address][]Balance
+	ticks    []TickInfo
+	txs      map[common.Hash]*TxStatus
+	// lims 小写tick到lim
+	lims map[string]decimal.Decimal
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		balances: make(map[common.Address][]Balance),
+		txs:      make(map[common.Hash]*TxStatus),
+		lims:     make(map[string]decimal.Decimal),
+	}
+}
+
+// SetBalance sets the balance of tick held by address.
+func (m *Memory) SetBalance(address common.Address, tick string, amount int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	balances := m.balances[address]
+	for i := range balances {
+		if equalTick(balances[i].Tick, tick) {
+			balances[i].Amount = amount
+			return
+		}
+	}
+	m.balances[address] = append(balances, Balance{Protocol: "crc-20", Tick: tick, Amount: amount})
+}
+
+// SetTick adds tick or replaces the tick with the same name.
+func (m *Memory) SetTick(tick TickInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.ticks {
+		if equalTick(m.ticks[i].Tick, tick.Tick) {
+			m.ticks[i] = tick
+			return
+		}
+	}
+	m.ticks = append(m.ticks, tick)
+}
+
+// SetLim sets the per-mint limit returned by Lim for tick.
+func (m *Memory) SetLim(tick string, lim decimal.Decimal) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lims[strings.ToLower(tick)] = lim
+}
+
+func (m *Memory) SetTxStatus(status TxStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.txs[status.Hash] = &status
+}
+
+func (m *Memory) Balances(ctx context.Context, address common.Address) ([]Balance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Balance(nil), m.balances[address]...), nil
+}
+
+func (m *Memory) Ticks(ctx context.Context, page int, size int) (*TicksPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return pageOf(m.ticks, page, size), nil
+}
+
+func (m *Memory) TickInfo(ctx context.Context, tick string) (*TickInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := range m.ticks {
+		if equalTick(m.ticks[i].Tick, tick) {
+			info := m.ticks[i]
+			return &info, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) TxStatus(ctx context.Context, hash common.Hash) (*TxStatus, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status, ok := m.txs[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	result := *status
+	return &result, nil
+}
+
+func (m *Memory) Lim(ctx context.Context, tick string) (decimal.Decimal, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lim, ok := m.lims[strings.ToLower(tick)]
+	if !ok {
+		return decimal.Zero, ErrNotFound
+	}
+	return lim, nil
+}
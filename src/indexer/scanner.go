@@ -0,0 +1,238 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"cronos-tools/src/inscription"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+	"math/big"
+	"time"
+)
+
+// BlockClient is the part of ethclient.Client the Scanner needs.
+type BlockClient interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// Scanner reads blocks over RPC and applies the CRC-20 inscriptions in them to a Local index:
+//   - deploy and mint must be sent to the sender's own address, transfer moves amt to the recipient
+//   - the first deploy of a tick wins, ticks are compared case-insensitively
+//   - a mint is valid when amt is at most lim and the minted supply stays at most max
+//   - a transfer is valid when the sender holds at least amt
+//
+// Inscriptions are applied in block and transaction order. Cronos has instant finality, so
+// indexed blocks are never rolled back.
+type Scanner struct {
+	client BlockClient
+	local  *Local
+	signer types.Signer
+	// OnBlock 每个区块写入数据库后调用，statuses是区块中的铭文交易
+	OnBlock func(number uint64, statuses []TxStatus)
+}
+
+func NewScanner(ctx context.Context, client BlockClient, local *Local) (*Scanner, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{client: client, local: local, signer: types.LatestSignerForChainID(chainID)}, nil
+}
+
+// Scan indexes blocks from to to, both included. Every block is committed on its own, so an
+// interrupted scan can continue from Local.Height()+1.
+func (s *Scanner) Scan(ctx context.Context, from uint64, to uint64) error {
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		block, err := s.client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return fmt.Errorf("fetch block %d: %w", number, err)
+		}
+		statuses, err := s.IndexBlock(ctx, block)
+		if err != nil {
+			return fmt.Errorf("index block %d: %w", number, err)
+		}
+		if s.OnBlock != nil {
+			s.OnBlock(number, statuses)
+		}
+	}
+	return nil
+}
+
+// IndexBlock applies the inscriptions in block and records it as the indexed height.
+func (s *Scanner) IndexBlock(ctx context.Context, block *types.Block) ([]TxStatus, error) {
+	writer := s.local.newBlockWriter()
+	var statuses []TxStatus
+	for _, tx := range block.Transactions() {
+		if tx.To() == nil || !bytes.HasPrefix(tx.Data(), []byte(inscription.Prefix)) {
+			continue
+		}
+		operation, err := inscription.Parse(tx.Data())
+		if errors.Is(err, inscription.ErrNoPrefix) || errors.Is(err, inscription.ErrNotCRC20) {
+			continue
+		}
+		status := TxStatus{Hash: tx.Hash()}
+		if err != nil {
+			status.Reason = err.Error()
+		} else {
+			status.Op = operation.Op()
+			status.Reason, err = s.apply(ctx, writer, block, tx, operation)
+			if err != nil {
+				return nil, err
+			}
+			status.Valid = status.Reason == ""
+			switch op := operation.(type) {
+			case inscription.Deploy:
+				status.Tick = op.Tick
+			case inscription.Mint:
+				status.Tick, status.Amt = op.Tick, op.Amt
+			case inscription.Transfer:
+				status.Tick, status.Amt = op.Tick, op.Amt
+			}
+		}
+		if err := writer.put(txKey(tx.Hash()), status); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	if err := writer.commit(block.NumberU64()); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// apply 执行一条铭文，返回铭文无效的原因，err只用于RPC和数据库错误
+func (s *Scanner) apply(ctx context.Context, writer *blockWriter, block *types.Block, tx *types.Transaction, operation inscription.Operation) (string, error) {
+	from, err := types.Sender(s.signer, tx)
+	if err != nil {
+		return "", fmt.Errorf("recover sender of %s: %w", tx.Hash().Hex(), err)
+	}
+	receipt, err := s.client.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		return "", fmt.Errorf("fetch receipt of %s: %w", tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return "transaction failed", nil
+	}
+	to := *tx.To()
+
+	switch op := operation.(type) {
+	case inscription.Deploy:
+		if to != from {
+			return "deploy must be sent to the sender itself", nil
+		}
+		var existing localTick
+		ok, err := writer.get(tickKey(op.Tick), &existing)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return "tick " + existing.Tick + " is already deployed", nil
+		}
+		var count int
+		if _, err := writer.get(tickCountKey, &count); err != nil {
+			return "", err
+		}
+		count++
+		maxAmount, _ := decimal.NewFromString(op.Max)
+		limAmount, _ := decimal.NewFromString(op.Lim)
+		tick := localTick{
+			TickInfo: TickInfo{
+				Id:          count,
+				Protocol:    inscription.Protocol,
+				Tick:        op.Tick,
+				DeployTime:  time.Unix(int64(block.Time()), 0).UTC(),
+				TotalSupply: maxAmount.IntPart(),
+			},
+			Max:         maxAmount,
+			Lim:         limAmount,
+			DeployTx:    tx.Hash(),
+			DeployBlock: block.NumberU64(),
+		}
+		if err := writer.put(tickCountKey, count); err != nil {
+			return "", err
+		}
+		return "", writer.put(tickKey(op.Tick), tick)
+
+	case inscription.Mint:
+		if to != from {
+			return "mint must be sent to the sender itself", nil
+		}
+		var tick localTick
+		ok, err := writer.get(tickKey(op.Tick), &tick)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "tick " + op.Tick + " is not deployed", nil
+		}
+		amount, _ := decimal.NewFromString(op.Amt)
+		if amount.GreaterThan(tick.Lim) {
+			return "amt " + op.Amt + " exceeds lim " + tick.Lim.String(), nil
+		}
+		if tick.Minted.Add(amount).GreaterThan(tick.Max) {
+			return "amt " + op.Amt + " exceeds the remaining supply " + tick.Max.Sub(tick.Minted).String(), nil
+		}
+		if err := addBalance(writer, &tick, from, amount); err != nil {
+			return "", err
+		}
+		tick.Minted = tick.Minted.Add(amount)
+		tick.MintedCount++
+		tick.Progress = tick.Minted.Div(tick.Max).Mul(decimal.NewFromInt(100)).Round(4).InexactFloat64()
+		return "", writer.put(tickKey(op.Tick), tick)
+
+	case inscription.Transfer:
+		var tick localTick
+		ok, err := writer.get(tickKey(op.Tick), &tick)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "tick " + op.Tick + " is not deployed", nil
+		}
+		amount, _ := decimal.NewFromString(op.Amt)
+		var balance localBalance
+		if _, err := writer.get(balanceKey(from, tick.Tick), &balance); err != nil {
+			return "", err
+		}
+		if balance.Amount.LessThan(amount) {
+			return "insufficient balance " + balance.Amount.String(), nil
+		}
+		if to == from {
+			return "", nil
+		}
+		if err := addBalance(writer, &tick, from, amount.Neg()); err != nil {
+			return "", err
+		}
+		if err := addBalance(writer, &tick, to, amount); err != nil {
+			return "", err
+		}
+		return "", writer.put(tickKey(op.Tick), tick)
+	}
+	return "unknown operation " + operation.Op(), nil
+}
+
+// addBalance 修改余额，余额在0和正数之间变化时同步更新tick的持有人数
+func addBalance(writer *blockWriter, tick *localTick, address common.Address, delta decimal.Decimal) error {
+	key := balanceKey(address, tick.Tick)
+	balance := localBalance{Tick: tick.Tick}
+	if _, err := writer.get(key, &balance); err != nil {
+		return err
+	}
+	before := balance.Amount.Sign()
+	balance.Amount = balance.Amount.Add(delta)
+	after := balance.Amount.Sign()
+	if before <= 0 && after > 0 {
+		tick.HolderCount++
+	} else if before > 0 && after <= 0 {
+		tick.HolderCount--
+	}
+	return writer.put(key, balance)
+}
@@ -0,0 +1,270 @@
+package indexer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+// fakeChain 只提供索引需要的交易回执
+type fakeChain struct {
+	chainID  *big.Int
+	receipts map[common.Hash]*types.Receipt
+}
+
+func (c *fakeChain) ChainID(ctx context.Context) (*big.Int, error) {
+	return c.chainID, nil
+}
+
+func (c *fakeChain) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeChain) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	receipt, ok := c.receipts[hash]
+	if !ok {
+		return nil, errors.New("receipt not found")
+	}
+	return receipt, nil
+}
+
+type scannerTest struct {
+	t       *testing.T
+	chain   *fakeChain
+	local   *Local
+	scanner *Scanner
+	nonce   uint64
+	height  uint64
+}
+
+func newScannerTest(t *testing.T) *scannerTest {
+	local, err := OpenLocal(filepath.Join(t.TempDir(), "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { local.Close() })
+	chain := &fakeChain{chainID: big.NewInt(25), receipts: make(map[common.Hash]*types.Receipt)}
+	scanner, err := NewScanner(context.Background(), chain, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &scannerTest{t: t, chain: chain, local: local, scanner: scanner}
+}
+
+// tx 签名一笔交易并记录它的回执状态
+func (s *scannerTest) tx(key *ecdsa.PrivateKey, to common.Address, payload string, status uint64) *types.Transaction {
+	s.nonce++
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(s.chain.chainID), &types.LegacyTx{
+		Nonce:    s.nonce,
+		To:       &to,
+		Gas:      30000,
+		GasPrice: big.NewInt(1),
+		Data:     []byte(payload),
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.chain.receipts[tx.Hash()] = &types.Receipt{Status: status, TxHash: tx.Hash()}
+	return tx
+}
+
+// block 索引包含txs的下一个区块
+func (s *scannerTest) block(txs ...*types.Transaction) []TxStatus {
+	s.height++
+	header := &types.Header{Number: new(big.Int).SetUint64(s.height), Time: 1700000000 + s.height}
+	statuses, err := s.scanner.IndexBlock(context.Background(), types.NewBlockWithHeader(header).WithBody(txs, nil))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return statuses
+}
+
+func (s *scannerTest) balance(address common.Address, tick string) int {
+	balances, err := s.local.Balances(context.Background(), address)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	for _, balance := range balances {
+		if equalTick(balance.Tick, tick) {
+			return balance.Amount
+		}
+	}
+	return 0
+}
+
+func (s *scannerTest) tick(tick string) *TickInfo {
+	info, err := s.local.TickInfo(context.Background(), tick)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return info
+}
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, common.Address) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func checkStatuses(t *testing.T, statuses []TxStatus, want []string) {
+	t.Helper()
+	if len(statuses) != len(want) {
+		t.Fatalf("got %d statuses, want %d: %+v", len(statuses), len(want), statuses)
+	}
+	for i, status := range statuses {
+		if status.Reason != want[i] || status.Valid != (want[i] == "") {
+			t.Errorf("status %d of %s %s = valid %v reason %q, want reason %q", i, status.Op, status.Tick, status.Valid, status.Reason, want[i])
+		}
+	}
+}
+
+func TestScannerDeploy(t *testing.T) {
+	s := newScannerTest(t)
+	alice, aliceAddress := newKey(t)
+	_, bobAddress := newKey(t)
+
+	statuses := s.block(
+		s.tx(alice, bobAddress, `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"1000","lim":"100"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"1000","lim":"100"}`, types.ReceiptStatusFailed),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"deploy","tick":"Cros","max":"1000","lim":"100"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"deploy","tick":"CROS","max":"5000","lim":"500"}`, types.ReceiptStatusSuccessful),
+		// 不是crc-20铭文的交易不记录
+		s.tx(alice, aliceAddress, `data:,hello`, types.ReceiptStatusSuccessful),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"deploy","tick":"bad","max":"10","lim":"100"}`, types.ReceiptStatusSuccessful),
+	)
+	checkStatuses(t, statuses, []string{
+		"deploy must be sent to the sender itself",
+		"transaction failed",
+		"",
+		"tick Cros is already deployed",
+		"invalid amount: lim 100 is bigger than max 10",
+	})
+
+	info := s.tick("cROS")
+	if info.Tick != "Cros" || info.Id != 1 || info.TotalSupply != 1000 {
+		t.Errorf("tick = %+v, want Cros with id 1 and total supply 1000", info)
+	}
+	if lim, err := s.local.Lim(context.Background(), "CROS"); err != nil || lim.String() != "100" {
+		t.Errorf("Lim() = %s %v, want 100", lim, err)
+	}
+	if _, err := s.local.TickInfo(context.Background(), "bad"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("TickInfo of an invalid deploy = %v, want ErrNotFound", err)
+	}
+	height, ok, err := s.local.Height()
+	if err != nil || !ok || height != 1 {
+		t.Errorf("Height() = %d %v %v, want 1", height, ok, err)
+	}
+	status, err := s.local.TxStatus(context.Background(), statuses[2].Hash)
+	if err != nil || !status.Valid || status.Op != "deploy" || status.Tick != "Cros" {
+		t.Errorf("TxStatus = %+v %v, want a valid deploy of Cros", status, err)
+	}
+}
+
+func TestScannerMint(t *testing.T) {
+	s := newScannerTest(t)
+	alice, aliceAddress := newKey(t)
+	bob, bobAddress := newKey(t)
+
+	statuses := s.block(
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"100"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"250","lim":"100"}`, types.ReceiptStatusSuccessful),
+		// 同一区块中后面的交易能读到前面的deploy
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"mint","tick":"CROS","amt":"100"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"101"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, bobAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"100"}`, types.ReceiptStatusSuccessful),
+		s.tx(bob, bobAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"100"}`, types.ReceiptStatusFailed),
+	)
+	checkStatuses(t, statuses, []string{
+		"tick cros is not deployed",
+		"",
+		"",
+		"amt 101 exceeds lim 100",
+		"mint must be sent to the sender itself",
+		"transaction failed",
+	})
+	if got := s.balance(aliceAddress, "cros"); got != 100 {
+		t.Errorf("balance of alice = %d, want 100", got)
+	}
+	if got := s.balance(bobAddress, "cros"); got != 0 {
+		t.Errorf("balance of bob = %d, want 0", got)
+	}
+
+	statuses = s.block(
+		s.tx(bob, bobAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"100"}`, types.ReceiptStatusSuccessful),
+		s.tx(bob, bobAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"100"}`, types.ReceiptStatusSuccessful),
+		s.tx(bob, bobAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"50"}`, types.ReceiptStatusSuccessful),
+		s.tx(bob, bobAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"1"}`, types.ReceiptStatusSuccessful),
+	)
+	checkStatuses(t, statuses, []string{
+		"",
+		"amt 100 exceeds the remaining supply 50",
+		"",
+		"amt 1 exceeds the remaining supply 0",
+	})
+	if got := s.balance(bobAddress, "cros"); got != 150 {
+		t.Errorf("balance of bob = %d, want 150", got)
+	}
+	info := s.tick("cros")
+	if info.MintedCount != 3 || info.HolderCount != 2 || info.Progress != 100 {
+		t.Errorf("tick = %+v, want 3 mints, 2 holders and progress 100", info)
+	}
+}
+
+func TestScannerTransfer(t *testing.T) {
+	s := newScannerTest(t)
+	alice, aliceAddress := newKey(t)
+	bob, bobAddress := newKey(t)
+
+	s.block(
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"1000","lim":"100"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"100"}`, types.ReceiptStatusSuccessful),
+	)
+	statuses := s.block(
+		s.tx(alice, bobAddress, `data:,{"p":"crc-20","op":"transfer","tick":"CROS","amt":"30"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, bobAddress, `data:,{"p":"crc-20","op":"transfer","tick":"cros","amt":"71"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, bobAddress, `data:,{"p":"crc-20","op":"transfer","tick":"cros","amt":"10"}`, types.ReceiptStatusFailed),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"transfer","tick":"cros","amt":"70"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, aliceAddress, `data:,{"p":"crc-20","op":"transfer","tick":"cros","amt":"71"}`, types.ReceiptStatusSuccessful),
+		s.tx(alice, bobAddress, `data:,{"p":"crc-20","op":"transfer","tick":"other","amt":"1"}`, types.ReceiptStatusSuccessful),
+	)
+	checkStatuses(t, statuses, []string{
+		"",
+		"insufficient balance 70",
+		"transaction failed",
+		// 转给自己余额不变
+		"",
+		"insufficient balance 70",
+		"tick other is not deployed",
+	})
+	if got := s.balance(aliceAddress, "cros"); got != 70 {
+		t.Errorf("balance of alice = %d, want 70", got)
+	}
+	if got := s.balance(bobAddress, "cros"); got != 30 {
+		t.Errorf("balance of bob = %d, want 30", got)
+	}
+	if info := s.tick("cros"); info.HolderCount != 2 {
+		t.Errorf("holders = %d, want 2", info.HolderCount)
+	}
+
+	statuses = s.block(
+		s.tx(bob, aliceAddress, `data:,{"p":"crc-20","op":"transfer","tick":"cros","amt":"30"}`, types.ReceiptStatusSuccessful),
+	)
+	checkStatuses(t, statuses, []string{""})
+	if got := s.balance(aliceAddress, "cros"); got != 100 {
+		t.Errorf("balance of alice = %d, want 100", got)
+	}
+	if got := s.balance(bobAddress, "cros"); got != 0 {
+		t.Errorf("balance of bob = %d, want 0", got)
+	}
+	if info := s.tick("cros"); info.HolderCount != 1 {
+		t.Errorf("holders = %d, want 1", info.HolderCount)
+	}
+}
@@ -0,0 +1,259 @@
+package inscription
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// Prefix 铭文数据的前缀
+	Prefix   = "data:,"
+	Protocol = "crc-20"
+
+	OpDeploy   = "deploy"
+	OpMint     = "mint"
+	OpTransfer = "transfer"
+
+	// MaxTickLength tick最多包含的字符数
+	MaxTickLength = 16
+)
+
+var (
+	ErrNoPrefix        = errors.New("payload does not start with " + Prefix)
+	ErrNotCRC20        = errors.New("payload is not a " + Protocol + " inscription")
+	ErrUnknownOp       = errors.New("unknown " + Protocol + " operation")
+	ErrInvalidTick     = errors.New("invalid tick")
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrMissingArgument = errors.New("missing argument")
+)
+
+// amountPattern 数量必须是不带符号、没有多余前导零的十进制字符串
+var amountPattern = regexp.MustCompile(`^(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// Operation is a CRC-20 operation that can be encoded into the data of a transaction.
+type Operation interface {
+	Op() string
+	Validate() error
+	// Encode returns the canonical data:, payload of the operation.
+	Encode() ([]byte, error)
+}
+
+type Deploy struct {
+	Tick string
+	Max  string
+	Lim  string
+}
+
+type Mint struct {
+	Tick string
+	Amt  string
+}
+
+type Transfer struct {
+	Tick string
+	Amt  string
+}
+
+// 以下结构体的字段顺序决定了编码后JSON的字段顺序
+type deployJSON struct {
+	P    string `json:"p"`
+	Op   string `json:"op"`
+	Tick string `json:"tick"`
+	Max  string `json:"max"`
+	Lim  string `json:"lim"`
+}
+
+type amountJSON struct {
+	P    string `json:"p"`
+	Op   string `json:"op"`
+	Tick string `json:"tick"`
+	Amt  string `json:"amt"`
+}
+
+func (d Deploy) Op() string {
+	return OpDeploy
+}
+
+func (d Deploy) Validate() error {
+	if err := ValidateTick(d.Tick); err != nil {
+		return err
+	}
+	if err := ValidateAmount("max", d.Max); err != nil {
+		return err
+	}
+	if err := ValidateAmount("lim", d.Lim); err != nil {
+		return err
+	}
+	maxAmount, _ := decimal.NewFromString(d.Max)
+	limAmount, _ := decimal.NewFromString(d.Lim)
+	if limAmount.GreaterThan(maxAmount) {
+		return fmt.Errorf("%w: lim %s is bigger than max %s", ErrInvalidAmount, d.Lim, d.Max)
+	}
+	return nil
+}
+
+func (d Deploy) Encode() ([]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return encode(deployJSON{P: Protocol, Op: OpDeploy, Tick: d.Tick, Max: d.Max, Lim: d.Lim})
+}
+
+func (m Mint) Op() string {
+	return OpMint
+}
+
+func (m Mint) Validate() error {
+	if err := ValidateTick(m.Tick); err != nil {
+		return err
+	}
+	return ValidateAmount("amt", m.Amt)
+}
+
+func (m Mint) Encode() ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return encode(amountJSON{P: Protocol, Op: OpMint, Tick: m.Tick, Amt: m.Amt})
+}
+
+func (t Transfer) Op() string {
+	return OpTransfer
+}
+
+func (t Transfer) Validate() error {
+	if err := ValidateTick(t.Tick); err != nil {
+		return err
+	}
+	return ValidateAmount("amt", t.Amt)
+}
+
+func (t Transfer) Encode() ([]byte, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return encode(amountJSON{P: Protocol, Op: OpTransfer, Tick: t.Tick, Amt: t.Amt})
+}
+
+func encode(v interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(Prefix)
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// json.Encoder会在末尾追加换行
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+// ValidateTick checks a tick is 1 to MaxTickLength printable characters without spaces or quotes.
+func ValidateTick(tick string) error {
+	if tick == "" {
+		return fmt.Errorf("%w: tick is empty", ErrInvalidTick)
+	}
+	if !utf8.ValidString(tick) {
+		return fmt.Errorf("%w: tick is not valid utf-8", ErrInvalidTick)
+	}
+	if length := utf8.RuneCountInString(tick); length > MaxTickLength {
+		return fmt.Errorf("%w: tick %q has %d characters, max %d", ErrInvalidTick, tick, length, MaxTickLength)
+	}
+	for _, r := range tick {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) || r == '"' || r == '\\' {
+			return fmt.Errorf("%w: tick %q contains %q", ErrInvalidTick, tick, r)
+		}
+	}
+	return nil
+}
+
+// ValidateAmount checks value is a positive decimal string such as "1000" or "0.5".
+func ValidateAmount(name string, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: %s", ErrMissingArgument, name)
+	}
+	if !amountPattern.MatchString(value) {
+		return fmt.Errorf("%w: %s %q is not a decimal string", ErrInvalidAmount, name, value)
+	}
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return fmt.Errorf("%w: %s %q: %v", ErrInvalidAmount, name, value, err)
+	}
+	if amount.Sign() <= 0 {
+		return fmt.Errorf("%w: %s must bigger than 0", ErrInvalidAmount, name)
+	}
+	return nil
+}
+
+// Parse decodes and validates a data:, payload. It returns ErrNoPrefix or ErrNotCRC20 for
+// payloads that are not CRC-20 inscriptions at all.
+func Parse(payload []byte) (Operation, error) {
+	if !bytes.HasPrefix(payload, []byte(Prefix)) {
+		return nil, ErrNoPrefix
+	}
+	content := bytes.TrimSpace(payload[len(Prefix):])
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, ErrNotCRC20
+	}
+	p, err := stringField(fields, "p")
+	if err != nil || p != Protocol {
+		return nil, ErrNotCRC20
+	}
+	op, err := stringField(fields, "op")
+	if err != nil {
+		return nil, err
+	}
+	tick, err := stringField(fields, "tick")
+	if err != nil {
+		return nil, err
+	}
+
+	var operation Operation
+	switch op {
+	case OpDeploy:
+		deploy := Deploy{Tick: tick}
+		if deploy.Max, err = stringField(fields, "max"); err != nil {
+			return nil, err
+		}
+		if deploy.Lim, err = stringField(fields, "lim"); err != nil {
+			return nil, err
+		}
+		operation = deploy
+	case OpMint, OpTransfer:
+		amt, err := stringField(fields, "amt")
+		if err != nil {
+			return nil, err
+		}
+		if op == OpMint {
+			operation = Mint{Tick: tick, Amt: amt}
+		} else {
+			operation = Transfer{Tick: tick, Amt: amt}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownOp, op)
+	}
+	if err := operation.Validate(); err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// stringField 读取JSON中的字符串字段，数字等其他类型视为无效
+func stringField(fields map[string]json.RawMessage, name string) (string, error) {
+	raw, ok := fields[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrMissingArgument, name)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("%s must be a string: %s", name, strings.TrimSpace(string(raw)))
+	}
+	return value, nil
+}
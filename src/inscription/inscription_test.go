@@ -0,0 +1,105 @@
+package inscription
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    Operation
+		err     error
+	}{
+		{name: "deploy", payload: `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"21000000","lim":"1000"}`, want: Deploy{Tick: "cros", Max: "21000000", Lim: "1000"}},
+		{name: "mint", payload: `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"1000"}`, want: Mint{Tick: "cros", Amt: "1000"}},
+		{name: "transfer", payload: `data:,{"p":"crc-20","op":"transfer","tick":"cros","amt":"0.5"}`, want: Transfer{Tick: "cros", Amt: "0.5"}},
+		{name: "field order and spaces", payload: `data:, { "amt": "1", "tick": "CROS", "op": "mint", "p": "crc-20" } `, want: Mint{Tick: "CROS", Amt: "1"}},
+		{name: "unicode tick", payload: `data:,{"p":"crc-20","op":"mint","tick":"克罗诺斯","amt":"1"}`, want: Mint{Tick: "克罗诺斯", Amt: "1"}},
+		{name: "no prefix", payload: `{"p":"crc-20","op":"mint","tick":"cros","amt":"1"}`, err: ErrNoPrefix},
+		{name: "plain text", payload: `data:,hello`, err: ErrNotCRC20},
+		{name: "unclosed json", payload: `data:,{"p":"crc-20","op":"mint"`, err: ErrNotCRC20},
+		{name: "other protocol", payload: `data:,{"p":"crc20","op":"mint","tick":"cros","amt":"1"}`, err: ErrNotCRC20},
+		{name: "unknown op", payload: `data:,{"p":"crc-20","op":"burn","tick":"cros","amt":"1"}`, err: ErrUnknownOp},
+		{name: "missing amt", payload: `data:,{"p":"crc-20","op":"mint","tick":"cros"}`, err: ErrMissingArgument},
+		{name: "missing lim", payload: `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"1000"}`, err: ErrMissingArgument},
+		{name: "empty tick", payload: `data:,{"p":"crc-20","op":"mint","tick":"","amt":"1"}`, err: ErrInvalidTick},
+		{name: "long tick", payload: `data:,{"p":"crc-20","op":"mint","tick":"abcdefghijklmnopq","amt":"1"}`, err: ErrInvalidTick},
+		{name: "tick with space", payload: `data:,{"p":"crc-20","op":"mint","tick":"cr os","amt":"1"}`, err: ErrInvalidTick},
+		{name: "zero amt", payload: `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"0"}`, err: ErrInvalidAmount},
+		{name: "negative amt", payload: `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"-1"}`, err: ErrInvalidAmount},
+		{name: "leading zero amt", payload: `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"01"}`, err: ErrInvalidAmount},
+		{name: "exponent amt", payload: `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"1e3"}`, err: ErrInvalidAmount},
+		{name: "lim above max", payload: `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"10","lim":"100"}`, err: ErrInvalidAmount},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			operation, err := Parse([]byte(test.payload))
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("Parse(%s) error = %v, want %v", test.payload, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%s) error = %v", test.payload, err)
+			}
+			if !reflect.DeepEqual(operation, test.want) {
+				t.Errorf("Parse(%s) = %#v, want %#v", test.payload, operation, test.want)
+			}
+		})
+	}
+}
+
+func TestParseNumberField(t *testing.T) {
+	_, err := Parse([]byte(`data:,{"p":"crc-20","op":"mint","tick":"cros","amt":1000}`))
+	if err == nil || errors.Is(err, ErrNotCRC20) {
+		t.Errorf("Parse of a numeric amt error = %v, want a field error", err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		operation Operation
+		want      string
+	}{
+		{Deploy{Tick: "cros", Max: "21000000", Lim: "1000"}, `data:,{"p":"crc-20","op":"deploy","tick":"cros","max":"21000000","lim":"1000"}`},
+		{Mint{Tick: "cros", Amt: "1000"}, `data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"1000"}`},
+		{Transfer{Tick: "<&>", Amt: "0.5"}, `data:,{"p":"crc-20","op":"transfer","tick":"<&>","amt":"0.5"}`},
+	}
+	for _, test := range tests {
+		payload, err := test.operation.Encode()
+		if err != nil {
+			t.Fatalf("Encode(%#v) error = %v", test.operation, err)
+		}
+		if string(payload) != test.want {
+			t.Errorf("Encode(%#v) = %s, want %s", test.operation, payload, test.want)
+		}
+		parsed, err := Parse(payload)
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", payload, err)
+		}
+		if !reflect.DeepEqual(parsed, test.operation) {
+			t.Errorf("Parse(Encode(%#v)) = %#v", test.operation, parsed)
+		}
+	}
+}
+
+func TestEncodeInvalid(t *testing.T) {
+	tests := []struct {
+		operation Operation
+		err       error
+	}{
+		{Mint{Tick: "cros"}, ErrMissingArgument},
+		{Mint{Tick: `cr"os`, Amt: "1"}, ErrInvalidTick},
+		{Transfer{Tick: "cros", Amt: "1.5.0"}, ErrInvalidAmount},
+		{Deploy{Tick: "cros", Max: "1", Lim: "2"}, ErrInvalidAmount},
+	}
+	for _, test := range tests {
+		if _, err := test.operation.Encode(); !errors.Is(err, test.err) {
+			t.Errorf("Encode(%#v) error = %v, want %v", test.operation, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package journal
+
+import (
+	"bufio"
+	"cronos-tools/src/txengine"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCommandMismatch is returned when resuming a job with a different command.
+	ErrCommandMismatch = errors.New("journal was written by another command")
+	// ErrAddressMismatch is returned when an account index of a resumed job derives a
+	// different address than the journal recorded, for example with another key source.
+	ErrAddressMismatch = errors.New("account address does not match the journal")
+)
+
+type State string
+
+const (
+	// StateStarted 任务的第一条记录，保存命令和参数
+	StateStarted State = "started"
+	StatePlanned State = "planned"
+	// StateSigned 交易已签名、即将发送，没有对应的sent记录时不知道节点是否收到
+	StateSigned    State = "signed"
+	StateSent      State = "sent"
+	StateConfirmed State = "confirmed"
+	StateFailed    State = "failed"
+)
+
+// Record is one line of a job journal.
+type Record struct {
+	Time         time.Time         `json:"time"`
+	State        State             `json:"state"`
+	Command      string            `json:"command,omitempty"`
+	Params       map[string]string `json:"params,omitempty"`
+	AccountIndex uint              `json:"account_index"`
+	Address      string            `json:"address,omitempty"`
+	Count        uint              `json:"count,omitempty"`
+	Nonce        uint64            `json:"nonce,omitempty"`
+	Hash         string            `json:"hash,omitempty"`
+	// RawTx 已签名交易，恢复任务时用于继续跟踪
+	RawTx       string `json:"raw_tx,omitempty"`
+	Status      string `json:"status,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+	GasUsed     uint64 `json:"gas_used,omitempty"`
+}
+
+// Journal appends the progress of a mint or collect run to a JSONL file, one file per job,
+// so an interrupted run can be resumed. Every record is written straight to the file.
+type Journal struct {
+	id   string
+	path string
+
+	mu      sync.Mutex
+	file    *os.File
+	indexes map[common.Address]uint
+}
+
+// DefaultDir is ~/.cronos-tools/jobs.
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".cronos-tools", "jobs")
+	}
+	return filepath.Join(home, ".cronos-tools", "jobs")
+}
+
+// Create starts a new journal in dir for command, recording its params.
+func Create(dir string, command string, params map[string]string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	// 随机后缀避免同一秒内启动的任务使用相同的id
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	id := fmt.Sprintf("%s-%s-%s", command, time.Now().Format("20060102-150405"), hex.EncodeToString(suffix))
+	path := filepath.Join(dir, id+".jsonl")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{id: id, path: path, file: file, indexes: make(map[common.Address]uint)}
+	if err := j.write(Record{State: StateStarted, Command: command, Params: params}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// Open reopens the journal of job id for appending and returns its records.
+func Open(dir string, id string) (*Journal, []Record, error) {
+	path := filepath.Join(dir, id+".jsonl")
+	records, err := read(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 || records[0].State != StateStarted {
+		return nil, nil, fmt.Errorf("journal %s has no start record", path)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	j := &Journal{id: id, path: path, file: file, indexes: make(map[common.Address]uint)}
+	for _, record := range records {
+		if record.Address != "" && record.State != StateStarted {
+			j.indexes[common.HexToAddress(record.Address)] = record.AccountIndex
+		}
+	}
+	return j, records, nil
+}
+
+func read(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// 进程被中断时最后一行可能不完整
+			break
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func (j *Journal) ID() string {
+	return j.id
+}
+
+func (j *Journal) Path() string {
+	return j.path
+}
+
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+func (j *Journal) write(record Record) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	record.Time = time.Now()
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(line, '\n'))
+	return err
+}
+
+// Planned records that count transactions are about to be sent from the account.
+func (j *Journal) Planned(accountIndex uint, address common.Address, count uint) error {
+	j.mu.Lock()
+	j.indexes[address] = accountIndex
+	j.mu.Unlock()
+	return j.write(Record{State: StatePlanned, AccountIndex: accountIndex, Address: address.Hex(), Count: count})
+}
+
+// Signed records a transaction that is about to be broadcast. It is written before sending so
+// a run killed during the broadcast does not send the account's transactions again on resume.
+func (j *Journal) Signed(accountIndex uint, address common.Address, tx *types.Transaction) error {
+	return j.writeTx(StateSigned, accountIndex, address, tx)
+}
+
+// Sent records a transaction accepted by the node.
+func (j *Journal) Sent(accountIndex uint, address common.Address, tx *types.Transaction) error {
+	return j.writeTx(StateSent, accountIndex, address, tx)
+}
+
+// writeTx 记录一笔已签名交易
+func (j *Journal) writeTx(state State, accountIndex uint, address common.Address, tx *types.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.indexes[address] = accountIndex
+	j.mu.Unlock()
+	return j.write(Record{
+		State:        state,
+		AccountIndex: accountIndex,
+		Address:      address.Hex(),
+		Nonce:        tx.Nonce(),
+		Hash:         tx.Hash().Hex(),
+		RawTx:        hex.EncodeToString(raw),
+	})
+}
+
+// Finished records the final state of a tracked transaction.
+func (j *Journal) Finished(confirmation *txengine.Confirmation) error {
+	state := StateConfirmed
+	if confirmation.Status != txengine.ConfirmStatusConfirmed {
+		state = StateFailed
+	}
+	j.mu.Lock()
+	accountIndex := j.indexes[confirmation.Account]
+	j.mu.Unlock()
+	return j.write(Record{
+		State:        state,
+		AccountIndex: accountIndex,
+		Address:      confirmation.Account.Hex(),
+		Nonce:        confirmation.Nonce,
+		Hash:         confirmation.Hash.Hex(),
+		Status:       string(confirmation.Status),
+		BlockNumber:  confirmation.BlockNumber,
+		GasUsed:      confirmation.GasUsed,
+	})
+}
+
+// AccountProgress is what a journal says about one account.
+type AccountProgress struct {
+	AccountIndex uint
+	// Address 任务日志记录的账户地址，没有记录时为空
+	Address   common.Address
+	Planned   uint
+	Confirmed uint
+	Failed    uint
+	// Pending 已发送但没有最终结果的交易，按nonce索引
+	Pending map[uint64]*types.Transaction
+	// Unsent 已签名但没有发送记录的交易，按nonce索引。进程可能在发送时被中断，
+	// 需要查询回执确认节点是否收到
+	Unsent map[uint64]*types.Transaction
+}
+
+// Done is the number of transactions that do not have to be sent again.
+func (p *AccountProgress) Done() uint {
+	return p.Confirmed + uint(len(p.Pending))
+}
+
+// Replay folds records into the progress of each account index.
+func Replay(records []Record) (map[uint]*AccountProgress, error) {
+	progress := make(map[uint]*AccountProgress)
+	get := func(accountIndex uint) *AccountProgress {
+		p, ok := progress[accountIndex]
+		if !ok {
+			p = &AccountProgress{
+				AccountIndex: accountIndex,
+				Pending:      make(map[uint64]*types.Transaction),
+				Unsent:       make(map[uint64]*types.Transaction),
+			}
+			progress[accountIndex] = p
+		}
+		return p
+	}
+	for _, record := range records {
+		if record.State != StateStarted && record.Address != "" {
+			p := get(record.AccountIndex)
+			address := common.HexToAddress(record.Address)
+			if p.Address != (common.Address{}) && p.Address != address {
+				return nil, fmt.Errorf("%w: account index %d is both %s and %s", ErrAddressMismatch, record.AccountIndex, p.Address.Hex(), address.Hex())
+			}
+			p.Address = address
+		}
+		switch record.State {
+		case StatePlanned:
+			get(record.AccountIndex).Planned = record.Count
+		case StateSigned, StateSent:
+			raw, err := hex.DecodeString(record.RawTx)
+			if err != nil {
+				return nil, err
+			}
+			tx := new(types.Transaction)
+			if err := tx.UnmarshalBinary(raw); err != nil {
+				return nil, err
+			}
+			p := get(record.AccountIndex)
+			if record.State == StateSigned {
+				p.Unsent[record.Nonce] = tx
+				continue
+			}
+			delete(p.Unsent, record.Nonce)
+			p.Pending[record.Nonce] = tx
+		case StateConfirmed, StateFailed:
+			p := get(record.AccountIndex)
+			if _, ok := p.Pending[record.Nonce]; !ok {
+				continue
+			}
+			delete(p.Pending, record.Nonce)
+			if record.State == StateConfirmed {
+				p.Confirmed++
+			} else {
+				p.Failed++
+			}
+		}
+	}
+	return progress, nil
+}
+
+// CheckAddresses verifies that every account index in progress still derives the address the
+// journal recorded.
+func CheckAddresses(progress map[uint]*AccountProgress, derive func(accountIndex uint) (common.Address, error)) error {
+	for accountIndex, p := range progress {
+		if p.Address == (common.Address{}) {
+			continue
+		}
+		address, err := derive(accountIndex)
+		if err != nil {
+			return err
+		}
+		if address != p.Address {
+			return fmt.Errorf("%w: account index %d is %s in the journal but %s now, check the key flags", ErrAddressMismatch, accountIndex, p.Address.Hex(), address.Hex())
+		}
+	}
+	return nil
+}
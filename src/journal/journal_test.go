@@ -0,0 +1,221 @@
+package journal
+
+import (
+	"cronos-tools/src/txengine"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+)
+
+var (
+	address0 = common.HexToAddress("0x00000000000000000000000000000000000000a0")
+	address1 = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+)
+
+func signedTx(t *testing.T, nonce uint64) *types.Transaction {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(25)), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       &address0,
+		Gas:      21000,
+		GasPrice: big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func finished(t *testing.T, j *Journal, account common.Address, tx *types.Transaction, status txengine.ConfirmStatus) {
+	err := j.Finished(&txengine.Confirmation{Account: account, Nonce: tx.Nonce(), Hash: tx.Hash(), Status: status})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Create(dir, "mint", map[string]string{"tick": "cros"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(j.ID(), "mint-") {
+		t.Errorf("ID() = %s, want the command as prefix", j.ID())
+	}
+	txs := []*types.Transaction{signedTx(t, 0), signedTx(t, 1), signedTx(t, 2), signedTx(t, 0)}
+	steps := []error{
+		j.Planned(0, address0, 3),
+		j.Planned(1, address1, 1),
+		j.Sent(0, address0, txs[0]),
+		j.Sent(0, address0, txs[1]),
+		j.Sent(0, address0, txs[2]),
+		j.Sent(1, address1, txs[3]),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	finished(t, j, address0, txs[0], txengine.ConfirmStatusConfirmed)
+	finished(t, j, address0, txs[1], txengine.ConfirmStatusReverted)
+	// 重复的结果只计一次
+	finished(t, j, address0, txs[0], txengine.ConfirmStatusConfirmed)
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	j, records, err := Open(dir, j.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Open后Finished仍能找到账户序号
+	finished(t, j, address1, txs[3], txengine.ConfirmStatusConfirmed)
+	j.Close()
+	_, records, err = Open(dir, j.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Command != "mint" || records[0].Params["tick"] != "cros" {
+		t.Errorf("start record = %+v, want command mint with its params", records[0])
+	}
+
+	progress, err := Replay(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := progress[0]
+	if p.Address != address0 || p.Planned != 3 || p.Confirmed != 1 || p.Failed != 1 || len(p.Pending) != 1 || p.Done() != 2 {
+		t.Errorf("progress of account 0 = %+v, want 3 planned, 1 confirmed, 1 failed and 1 pending", p)
+	}
+	if pending := p.Pending[2]; pending == nil || pending.Hash() != txs[2].Hash() {
+		t.Errorf("pending transaction of nonce 2 = %v, want %s", pending, txs[2].Hash().Hex())
+	}
+	p = progress[1]
+	if p.Address != address1 || p.Planned != 1 || p.Confirmed != 1 || len(p.Pending) != 0 || p.Done() != 1 {
+		t.Errorf("progress of account 1 = %+v, want 1 planned and 1 confirmed", p)
+	}
+}
+
+func TestOpenTruncated(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Create(dir, "collect", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Planned(0, address0, 2); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+	// 进程中断时写了一半的记录
+	file, err := os.OpenFile(j.Path(), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"state":"sent","account_index":0,"raw_`)
+	file.Close()
+
+	_, records, err := Open(dir, j.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Errorf("got %d records, want 2", len(records))
+	}
+}
+
+func TestCreateUniqueID(t *testing.T) {
+	dir := t.TempDir()
+	ids := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		j, err := Create(dir, "mint", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.Close()
+		if ids[j.ID()] {
+			t.Fatalf("Create returned id %s twice", j.ID())
+		}
+		ids[j.ID()] = true
+	}
+}
+
+func TestReplayAddressMismatch(t *testing.T) {
+	records := []Record{
+		{State: StateStarted, Command: "mint"},
+		{State: StatePlanned, AccountIndex: 0, Address: address0.Hex(), Count: 1},
+		{State: StatePlanned, AccountIndex: 0, Address: address1.Hex(), Count: 1},
+	}
+	if _, err := Replay(records); !errors.Is(err, ErrAddressMismatch) {
+		t.Errorf("Replay error = %v, want %v", err, ErrAddressMismatch)
+	}
+}
+
+func TestCheckAddresses(t *testing.T) {
+	progress := map[uint]*AccountProgress{
+		0: {AccountIndex: 0, Address: address0},
+		1: {AccountIndex: 1, Address: address1},
+		// 没有记录地址的账户不检查
+		2: {AccountIndex: 2},
+	}
+	derived := map[uint]common.Address{0: address0, 1: address1}
+	derive := func(accountIndex uint) (common.Address, error) {
+		return derived[accountIndex], nil
+	}
+	if err := CheckAddresses(progress, derive); err != nil {
+		t.Errorf("CheckAddresses error = %v", err)
+	}
+	derived[1] = address0
+	if err := CheckAddresses(progress, derive); !errors.Is(err, ErrAddressMismatch) {
+		t.Errorf("CheckAddresses error = %v, want %v", err, ErrAddressMismatch)
+	}
+}
+
+func TestReplaySigned(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Create(dir, "mint", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txs := []*types.Transaction{signedTx(t, 0), signedTx(t, 1), signedTx(t, 1)}
+	steps := []error{
+		j.Planned(0, address0, 3),
+		j.Signed(0, address0, txs[0]),
+		j.Sent(0, address0, txs[0]),
+		// nonce 1第一次发送被拒绝，重新签名后发送成功
+		j.Signed(0, address0, txs[1]),
+		j.Signed(0, address0, txs[2]),
+		j.Sent(0, address0, txs[2]),
+		// 进程在发送nonce 2时被中断
+		j.Signed(0, address0, signedTx(t, 2)),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Close()
+
+	_, records, err := Open(dir, j.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress, err := Replay(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := progress[0]
+	if len(p.Pending) != 2 || p.Pending[1].Hash() != txs[2].Hash() || p.Done() != 2 {
+		t.Errorf("pending = %v, want nonce 0 and the resigned nonce 1", p.Pending)
+	}
+	if len(p.Unsent) != 1 || p.Unsent[2] == nil {
+		t.Errorf("unsent = %v, want only nonce 2", p.Unsent)
+	}
+}
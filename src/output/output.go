@@ -0,0 +1,226 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatCSV   Format = "csv"
+)
+
+var ErrUnknownFormat = errors.New("unknown output format")
+
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case FormatTable, FormatJSON, FormatCSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w %q, must be table, json or csv", ErrUnknownFormat, value)
+}
+
+// Writer renders records, which are structs or pointers to structs, as an aligned table, CSV
+// or JSON Lines. Columns are the json names of the exported fields. A record of a different
+// type than the previous one starts a new section with its own header. Writer is safe for
+// concurrent use.
+type Writer struct {
+	mu      sync.Mutex
+	format  Format
+	w       io.Writer
+	table   *tabwriter.Writer
+	csv     *csv.Writer
+	rowType reflect.Type
+	// columns 当前记录类型输出的字段下标
+	columns  []int
+	sections int
+	// Stream 为true时表格的每一行立即输出，列宽取表头和第一行中较宽的一个
+	Stream bool
+	// widths 逐行输出表格时当前记录类型的列宽
+	widths []int
+}
+
+func NewWriter(w io.Writer, format Format) *Writer {
+	writer := &Writer{format: format, w: w}
+	switch format {
+	case FormatTable:
+		writer.table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	case FormatCSV:
+		writer.csv = csv.NewWriter(w)
+	}
+	return writer
+}
+
+// Write renders one record. Table output is aligned and only written on Flush unless Stream
+// is set, CSV and JSON are written immediately.
+func (w *Writer) Write(record interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.format == FormatJSON {
+		encoder := json.NewEncoder(w.w)
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(record)
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(record))
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("output record must be a struct, got %T", record)
+	}
+	var header []string
+	if value.Type() != w.rowType {
+		header = w.setType(value.Type())
+	}
+	row := make([]string, len(w.columns))
+	for i, field := range w.columns {
+		row[i] = formatValue(value.Field(field))
+	}
+
+	if w.format == FormatCSV {
+		if header != nil {
+			if w.sections > 1 {
+				// 不同类型的记录之间空一行
+				w.csv.Write(nil)
+			}
+			if err := w.csv.Write(header); err != nil {
+				return err
+			}
+		}
+		if err := w.csv.Write(row); err != nil {
+			return err
+		}
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	if w.Stream {
+		return w.writeLine(header, row)
+	}
+	if header != nil {
+		if w.sections > 1 {
+			// 不同类型的记录之间空一行，并且分别对齐
+			if err := w.table.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintln(w.w)
+		}
+		fmt.Fprintln(w.table, strings.ToUpper(strings.Join(header, "\t")))
+	}
+	_, err := fmt.Fprintln(w.table, strings.Join(row, "\t"))
+	return err
+}
+
+// writeLine 按固定列宽立即输出表格的一行，header不为空时先输出表头
+func (w *Writer) writeLine(header []string, row []string) error {
+	if header != nil {
+		if w.sections > 1 {
+			fmt.Fprintln(w.w)
+		}
+		w.widths = make([]int, len(header))
+		titles := make([]string, len(header))
+		for i := range header {
+			titles[i] = strings.ToUpper(header[i])
+			w.widths[i] = len(header[i])
+			if len(row[i]) > w.widths[i] {
+				w.widths[i] = len(row[i])
+			}
+		}
+		if _, err := fmt.Fprintln(w.w, w.pad(titles)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w.w, w.pad(row))
+	return err
+}
+
+// pad 除最后一列外按列宽补齐，列之间至少空两格
+func (w *Writer) pad(cells []string) string {
+	var line strings.Builder
+	for i, cell := range cells {
+		line.WriteString(cell)
+		if i == len(cells)-1 {
+			break
+		}
+		padding := 2
+		if len(cell) < w.widths[i] {
+			padding += w.widths[i] - len(cell)
+		}
+		line.WriteString(strings.Repeat(" ", padding))
+	}
+	return line.String()
+}
+
+// setType 切换到新的记录类型，返回它的表头
+func (w *Writer) setType(rowType reflect.Type) []string {
+	w.rowType = rowType
+	w.columns = w.columns[:0]
+	w.sections++
+	var header []string
+	for i := 0; i < rowType.NumField(); i++ {
+		field := rowType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		w.columns = append(w.columns, i)
+		header = append(header, name)
+	}
+	return header
+}
+
+// formatValue 表格和CSV中单元格的文本
+func formatValue(value reflect.Value) string {
+	if value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+		// *big.Int等类型的String方法定义在指针上
+		if _, isTime := value.Elem().Interface().(time.Time); !isTime {
+			if v, ok := value.Interface().(fmt.Stringer); ok {
+				return v.String()
+			}
+		}
+		value = value.Elem()
+	}
+	switch v := value.Interface().(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// Flush writes a buffered table.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch w.format {
+	case FormatTable:
+		return w.table.Flush()
+	case FormatCSV:
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type balanceRow struct {
+	Address string   `json:"address"`
+	Tick    string   `json:"tick"`
+	Amount  *big.Int `json:"amount"`
+	secret  string
+	Skipped string `json:"-"`
+}
+
+type tickRow struct {
+	Tick       string    `json:"tick"`
+	DeployTime time.Time `json:"deploy_time,omitempty"`
+	Holders    int
+}
+
+func TestParseFormat(t *testing.T) {
+	for value, want := range map[string]Format{"table": FormatTable, " JSON ": FormatJSON, "csv": FormatCSV} {
+		if got, err := ParseFormat(value); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %s %v, want %s", value, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("yaml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("ParseFormat(yaml) error = %v, want %v", err, ErrUnknownFormat)
+	}
+}
+
+func write(t *testing.T, format Format, records ...interface{}) string {
+	var buffer bytes.Buffer
+	w := NewWriter(&buffer, format)
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.String()
+}
+
+var deployTime = time.Date(2023, 12, 1, 8, 0, 0, 0, time.UTC)
+
+func testRecords() []interface{} {
+	return []interface{}{
+		balanceRow{Address: "0xa0", Tick: "cros", Amount: big.NewInt(1000), secret: "x", Skipped: "y"},
+		&balanceRow{Address: "0xa1", Tick: "crosmos"},
+		tickRow{Tick: "cros", DeployTime: deployTime, Holders: 12},
+	}
+}
+
+func TestWriterTable(t *testing.T) {
+	want := "ADDRESS  TICK     AMOUNT\n" +
+		"0xa0     cros     1000\n" +
+		"0xa1     crosmos  \n" +
+		"\n" +
+		"TICK  DEPLOY_TIME           HOLDERS\n" +
+		"cros  2023-12-01T08:00:00Z  12\n"
+	if got := write(t, FormatTable, testRecords()...); got != want {
+		t.Errorf("table output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriterTableStream(t *testing.T) {
+	var buffer bytes.Buffer
+	w := NewWriter(&buffer, FormatTable)
+	w.Stream = true
+	records := testRecords()
+	if err := w.Write(records[0]); err != nil {
+		t.Fatal(err)
+	}
+	// 每一行不等Flush就输出
+	if want := "ADDRESS  TICK  AMOUNT\n0xa0     cros  1000\n"; buffer.String() != want {
+		t.Errorf("table output before Flush =\n%s\nwant\n%s", buffer.String(), want)
+	}
+	for _, record := range records[1:] {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "ADDRESS  TICK  AMOUNT\n" +
+		"0xa0     cros  1000\n" +
+		"0xa1     crosmos  \n" +
+		"\n" +
+		"TICK  DEPLOY_TIME           HOLDERS\n" +
+		"cros  2023-12-01T08:00:00Z  12\n"
+	if buffer.String() != want {
+		t.Errorf("streamed table output =\n%s\nwant\n%s", buffer.String(), want)
+	}
+}
+
+func TestWriterCSV(t *testing.T) {
+	want := "address,tick,amount\n" +
+		"0xa0,cros,1000\n" +
+		"0xa1,crosmos,\n" +
+		"\n" +
+		"tick,deploy_time,Holders\n" +
+		"cros,2023-12-01T08:00:00Z,12\n"
+	if got := write(t, FormatCSV, testRecords()...); got != want {
+		t.Errorf("csv output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriterJSON(t *testing.T) {
+	want := `{"address":"0xa0","tick":"cros","amount":1000}` + "\n" +
+		`{"address":"0xa1","tick":"crosmos","amount":null}` + "\n" +
+		`{"tick":"cros","deploy_time":"2023-12-01T08:00:00Z","Holders":12}` + "\n"
+	if got := write(t, FormatJSON, testRecords()...); got != want {
+		t.Errorf("json output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriterRejectsNonStruct(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{}, FormatTable)
+	if err := w.Write("text"); err == nil {
+		t.Error("Write of a string = nil, want an error")
+	}
+}
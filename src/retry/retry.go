@@ -0,0 +1,130 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy decides how often and how long to wait before a failed call is tried again. Delays
+// grow exponentially from InitialDelay by Multiplier up to MaxDelay, each one randomly moved by
+// up to Jitter of itself so that many workers failing together do not retry together.
+type Policy struct {
+	// MaxAttempts 最多调用的次数，包括第一次，小于1时按1处理
+	MaxAttempts int
+	// InitialDelay 第一次重试前的等待时间
+	InitialDelay time.Duration
+	// MaxDelay 等待时间的上限
+	MaxDelay time.Duration
+	// Multiplier 每次重试后等待时间的倍数
+	Multiplier float64
+	// Jitter 等待时间随机浮动的比例，0到1之间
+	Jitter float64
+	// CallTimeout 每次调用的超时时间，0表示只受ctx限制
+	CallTimeout time.Duration
+	// Retryable 判断错误是否值得重试，为空时重试所有错误
+	Retryable func(err error) bool
+}
+
+// Default retries 5 times in total, waiting 1s, 2s, 4s and 8s with 20% jitter, each call
+// limited to 20s.
+func Default() Policy {
+	return Policy{
+		MaxAttempts:  5,
+		InitialDelay: time.Second,
+		MaxDelay:     30 * time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		CallTimeout:  20 * time.Second,
+	}
+}
+
+// Delay is how long to wait after the given failed attempt, counting from 0.
+func (p Policy) Delay(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// WithTimeout derives the context of a single call from ctx.
+func (p Policy) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.CallTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, p.CallTimeout)
+}
+
+// Do calls fn until it succeeds, returns an error Retryable rejects or MaxAttempts calls have
+// failed, waiting between calls as the policy says. It stops early when ctx is done. action
+// names the call in logs and in the returned error.
+func (p Policy) Do(ctx context.Context, action string, fn func(ctx context.Context) error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	for attempt := 0; ; attempt++ {
+		callCtx, cancel := p.WithTimeout(ctx)
+		err := fn(callCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if p.Retryable != nil && !p.Retryable(err) {
+			return err
+		}
+		if attempt+1 >= maxAttempts {
+			if maxAttempts == 1 {
+				return err
+			}
+			return fmt.Errorf("can not %s after %d attempts: %w", action, maxAttempts, err)
+		}
+		delay := p.Delay(attempt)
+		log.Println("Can not", action, err, "retry in", delay.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Value is Do for calls returning a value.
+func Value[T any](ctx context.Context, p Policy, action string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := p.Do(ctx, action, func(ctx context.Context) (err error) {
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+// Validate checks that the policy can be used.
+func (p Policy) Validate() error {
+	switch {
+	case p.MaxAttempts < 1:
+		return errors.New("retry max attempts must be at least 1")
+	case p.InitialDelay < 0 || p.MaxDelay < 0 || p.CallTimeout < 0:
+		return errors.New("retry delays and timeout can not be negative")
+	case p.Multiplier < 1:
+		return errors.New("retry multiplier must be at least 1")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return errors.New("retry jitter must be between 0 and 1")
+	}
+	return nil
+}
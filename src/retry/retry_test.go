@@ -0,0 +1,159 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient")
+
+func TestDelay(t *testing.T) {
+	p := Policy{InitialDelay: time.Second, MaxDelay: 5 * time.Second, Multiplier: 2}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for attempt, w := range want {
+		if got := p.Delay(attempt); got != w {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, got, w)
+		}
+	}
+
+	// 倍数小于1时按固定间隔等待
+	p = Policy{InitialDelay: time.Second}
+	if got := p.Delay(3); got != time.Second {
+		t.Errorf("Delay(3) without multiplier = %s, want 1s", got)
+	}
+}
+
+func TestDelayJitter(t *testing.T) {
+	p := Policy{InitialDelay: time.Second, Multiplier: 2, Jitter: 0.2}
+	varied := false
+	for i := 0; i < 100; i++ {
+		got := p.Delay(1)
+		if got < 1600*time.Millisecond || got > 2400*time.Millisecond {
+			t.Fatalf("Delay(1) = %s, want between 1.6s and 2.4s", got)
+		}
+		if got != 2*time.Second {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Error("Delay with jitter always returned 2s")
+	}
+}
+
+func fastPolicy(maxAttempts int) Policy {
+	return Policy{MaxAttempts: maxAttempts, InitialDelay: time.Millisecond, Multiplier: 1}
+}
+
+func TestDo(t *testing.T) {
+	calls := 0
+	err := fastPolicy(5).Do(context.Background(), "call", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Do = %v after %d calls, want success after 3", err, calls)
+	}
+}
+
+func TestDoExhausted(t *testing.T) {
+	calls := 0
+	err := fastPolicy(3).Do(context.Background(), "call", func(ctx context.Context) error {
+		calls++
+		return errTransient
+	})
+	if !errors.Is(err, errTransient) || calls != 3 {
+		t.Errorf("Do = %v after %d calls, want %v after 3", err, calls, errTransient)
+	}
+	if err.Error() != "can not call after 3 attempts: transient" {
+		t.Errorf("Do error = %q", err)
+	}
+
+	// 只调用一次时原样返回错误
+	err = fastPolicy(1).Do(context.Background(), "call", func(ctx context.Context) error {
+		return errTransient
+	})
+	if err != errTransient {
+		t.Errorf("Do with one attempt = %v, want %v", err, errTransient)
+	}
+}
+
+func TestDoNotRetryable(t *testing.T) {
+	errFatal := errors.New("fatal")
+	p := fastPolicy(5)
+	p.Retryable = func(err error) bool { return errors.Is(err, errTransient) }
+	calls := 0
+	err := p.Do(context.Background(), "call", func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return errTransient
+		}
+		return errFatal
+	})
+	if err != errFatal || calls != 2 {
+		t.Errorf("Do = %v after %d calls, want %v after 2", err, calls, errFatal)
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxAttempts: 5, InitialDelay: time.Hour}
+	calls := 0
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := p.Do(ctx, "call", func(ctx context.Context) error {
+		calls++
+		return errTransient
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("Do = %v after %d calls, want %v after 1", err, calls, context.Canceled)
+	}
+}
+
+func TestDoCallTimeout(t *testing.T) {
+	p := fastPolicy(2)
+	p.CallTimeout = 5 * time.Millisecond
+	calls := 0
+	err := p.Do(context.Background(), "call", func(ctx context.Context) error {
+		calls++
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || calls != 2 {
+		t.Errorf("Do = %v after %d calls, want %v after 2", err, calls, context.DeadlineExceeded)
+	}
+}
+
+func TestValue(t *testing.T) {
+	calls := 0
+	value, err := Value(context.Background(), fastPolicy(3), "call", func(ctx context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errTransient
+		}
+		return 42, nil
+	})
+	if err != nil || value != 42 {
+		t.Errorf("Value = %d %v, want 42", value, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Default().Validate() = %v", err)
+	}
+	invalid := []Policy{
+		{MaxAttempts: 0, Multiplier: 1},
+		{MaxAttempts: 1, Multiplier: 1, InitialDelay: -time.Second},
+		{MaxAttempts: 1, Multiplier: 0.5},
+		{MaxAttempts: 1, Multiplier: 1, Jitter: 1.5},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", p)
+		}
+	}
+}
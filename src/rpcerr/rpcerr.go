@@ -0,0 +1,185 @@
+package rpcerr
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/rpc"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 节点拒绝交易的原因，由Classify从go-ethereum和Cronos/Ethermint的错误中识别
+var (
+	ErrNonceTooLow       = errors.New("nonce too low")
+	ErrNonceTooHigh      = errors.New("nonce too high")
+	ErrAlreadyKnown      = errors.New("already known")
+	ErrUnderpriced       = errors.New("transaction underpriced")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrMempoolFull       = errors.New("mempool is full")
+	ErrRateLimited       = errors.New("rate limited")
+)
+
+// Policy is how a sender recovers from a class of error.
+type Policy string
+
+const (
+	// PolicyFail 未知错误，停止发送
+	PolicyFail Policy = "fail"
+	// PolicyResyncNonce 从链上重新同步nonce后用新nonce重发
+	PolicyResyncNonce Policy = "resync-nonce"
+	// PolicyTreatAsSent 节点已经有这笔交易，按发送成功处理
+	PolicyTreatAsSent Policy = "treat-as-sent"
+	// PolicyBumpFee 提高gas价格后重发
+	PolicyBumpFee Policy = "bump-fee"
+	// PolicySkipAccount 账户余额不足，跳过该账户
+	PolicySkipAccount Policy = "skip-account"
+	// PolicyBackoff 节点暂时无法处理，等待一段时间后重发
+	PolicyBackoff Policy = "backoff"
+)
+
+var policies = map[error]Policy{
+	ErrNonceTooLow:       PolicyResyncNonce,
+	ErrNonceTooHigh:      PolicyResyncNonce,
+	ErrAlreadyKnown:      PolicyTreatAsSent,
+	ErrUnderpriced:       PolicyBumpFee,
+	ErrInsufficientFunds: PolicySkipAccount,
+	ErrMempoolFull:       PolicyBackoff,
+	ErrRateLimited:       PolicyBackoff,
+}
+
+// Error is an error from a node together with its class. errors.Is matches both the class
+// sentinel and the original error.
+type Error struct {
+	Kind error
+	Err  error
+	// Got 和Expected 是节点返回的交易nonce和账户当前nonce，节点没有返回时为0
+	Got      uint64
+	Expected uint64
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Ethermint使用的cosmos-sdk ABCI错误码
+var abciCodes = map[int]error{
+	5:  ErrInsufficientFunds,
+	13: ErrUnderpriced,
+	19: ErrAlreadyKnown,
+	20: ErrMempoolFull,
+}
+
+// cosmos-sdk中nonce错误的两个错误码，需要根据got和expected区分过低还是过高
+const (
+	abciInvalidSequence = 3
+	abciWrongSequence   = 32
+)
+
+var (
+	// codespace sdk code 19、codespace: sdk, code: 19 或JSON中的"codespace":"sdk","code":19
+	abciCodePattern = regexp.MustCompile(`codespace"?:?\s*"?sdk"?,?\s*"?code"?:?\s*(\d+)`)
+	// Ethermint: invalid nonce; got 3, expected 5: invalid sequence
+	gotExpectedPattern = regexp.MustCompile(`got:?\s*(\d+),?\s*expected:?\s*(\d+)`)
+	// cosmos-sdk: account sequence mismatch, expected 5, got 3: incorrect account sequence
+	expectedGotPattern = regexp.MustCompile(`expected:?\s*(\d+),?\s*got:?\s*(\d+)`)
+)
+
+// 按顺序匹配错误信息，先匹配更具体的信息
+var messages = []struct {
+	text string
+	kind error
+}{
+	{"nonce too low", ErrNonceTooLow},
+	{"nonce too high", ErrNonceTooHigh},
+	{"invalid nonce", ErrNonceTooLow},
+	{"invalid sequence", ErrNonceTooLow},
+	{"incorrect account sequence", ErrNonceTooLow},
+	{"already known", ErrAlreadyKnown},
+	{"known transaction", ErrAlreadyKnown},
+	{"tx already in mempool", ErrAlreadyKnown},
+	{"underpriced", ErrUnderpriced},
+	{"insufficient fee", ErrUnderpriced},
+	{"fee per gas less than block base fee", ErrUnderpriced},
+	{"gas price too low", ErrUnderpriced},
+	{"insufficient funds", ErrInsufficientFunds},
+	{"txpool is full", ErrMempoolFull},
+	{"mempool is full", ErrMempoolFull},
+	{"rate limit", ErrRateLimited},
+	{"too many requests", ErrRateLimited},
+}
+
+// Classify wraps err in an *Error when it is one of the known node errors and returns err
+// unchanged otherwise.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests {
+		return &Error{Kind: ErrRateLimited, Err: err}
+	}
+
+	message := strings.ToLower(err.Error())
+	result := &Error{Err: err}
+	if match := abciCodePattern.FindStringSubmatch(message); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		if kind, ok := abciCodes[code]; ok {
+			result.Kind = kind
+		} else if code == abciInvalidSequence || code == abciWrongSequence {
+			result.Kind = ErrNonceTooLow
+		}
+	}
+	if result.Kind == nil {
+		for _, m := range messages {
+			if strings.Contains(message, m.text) {
+				result.Kind = m.kind
+				break
+			}
+		}
+	}
+	if result.Kind == nil {
+		return err
+	}
+	if result.Kind != ErrNonceTooLow && result.Kind != ErrNonceTooHigh {
+		return result
+	}
+	// 节点给出了期望的nonce时以它为准区分过低和过高
+	if match := gotExpectedPattern.FindStringSubmatch(message); match != nil {
+		result.Got, result.Expected = parseUint(match[1]), parseUint(match[2])
+	} else if match := expectedGotPattern.FindStringSubmatch(message); match != nil {
+		result.Expected, result.Got = parseUint(match[1]), parseUint(match[2])
+	} else {
+		return result
+	}
+	if result.Got > result.Expected {
+		result.Kind = ErrNonceTooHigh
+	} else if result.Got < result.Expected {
+		result.Kind = ErrNonceTooLow
+	}
+	return result
+}
+
+// PolicyOf classifies err and returns how to recover from it.
+func PolicyOf(err error) Policy {
+	err = Classify(err)
+	for kind, policy := range policies {
+		if errors.Is(err, kind) {
+			return policy
+		}
+	}
+	return PolicyFail
+}
+
+func parseUint(value string) uint64 {
+	n, _ := strconv.ParseUint(value, 10, 64)
+	return n
+}
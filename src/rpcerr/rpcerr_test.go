@@ -0,0 +1,101 @@
+package rpcerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/rpc"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		kind     error
+		got      uint64
+		expected uint64
+		policy   Policy
+	}{
+		// go-ethereum
+		{name: "geth nonce too low", err: errors.New("nonce too low: address 0x01, tx: 3 state: 5"), kind: ErrNonceTooLow, policy: PolicyResyncNonce},
+		{name: "geth nonce too high", err: errors.New("nonce too high"), kind: ErrNonceTooHigh, policy: PolicyResyncNonce},
+		{name: "geth already known", err: errors.New("already known"), kind: ErrAlreadyKnown, policy: PolicyTreatAsSent},
+		{name: "geth replacement underpriced", err: errors.New("replacement transaction underpriced"), kind: ErrUnderpriced, policy: PolicyBumpFee},
+		{name: "geth transaction underpriced", err: errors.New("transaction underpriced: tip needed 1, tip permitted 0"), kind: ErrUnderpriced, policy: PolicyBumpFee},
+		{name: "geth insufficient funds", err: errors.New("insufficient funds for gas * price + value: address 0x01 have 0 want 21000"), kind: ErrInsufficientFunds, policy: PolicySkipAccount},
+		{name: "geth txpool full", err: errors.New("txpool is full"), kind: ErrMempoolFull, policy: PolicyBackoff},
+		// Ethermint
+		{name: "ethermint nonce ahead", err: errors.New("invalid nonce; got 7, expected 5: invalid sequence"), kind: ErrNonceTooHigh, got: 7, expected: 5, policy: PolicyResyncNonce},
+		{name: "ethermint nonce behind", err: errors.New("invalid nonce; got 3, expected 5: invalid sequence"), kind: ErrNonceTooLow, got: 3, expected: 5, policy: PolicyResyncNonce},
+		{name: "cosmos sequence behind", err: errors.New("account sequence mismatch, expected 5, got 3: incorrect account sequence"), kind: ErrNonceTooLow, got: 3, expected: 5, policy: PolicyResyncNonce},
+		{name: "cosmos sequence ahead", err: errors.New("account sequence mismatch, expected 5, got 9: incorrect account sequence"), kind: ErrNonceTooHigh, got: 9, expected: 5, policy: PolicyResyncNonce},
+		{name: "ethermint fee", err: errors.New("provided fee < minimum global fee (100 < 200). Please increase the gas price.: insufficient fee"), kind: ErrUnderpriced, policy: PolicyBumpFee},
+		{name: "ethermint base fee", err: errors.New("max fee per gas less than block base fee (1 < 5000000000000)"), kind: ErrUnderpriced, policy: PolicyBumpFee},
+		{name: "ethermint mempool", err: errors.New("tx already in mempool"), kind: ErrAlreadyKnown, policy: PolicyTreatAsSent},
+		// cosmos-sdk ABCI错误码
+		{name: "abci code 19", err: errors.New("broadcast failed: codespace sdk code 19: tx already in mempool"), kind: ErrAlreadyKnown, policy: PolicyTreatAsSent},
+		{name: "abci code 19 json", err: errors.New(`{"codespace": "sdk", "code": 19}`), kind: ErrAlreadyKnown, policy: PolicyTreatAsSent},
+		{name: "abci code 20", err: errors.New("codespace sdk code 20: mempool is full"), kind: ErrMempoolFull, policy: PolicyBackoff},
+		{name: "abci code 32", err: errors.New("codespace sdk code 32: account sequence mismatch, expected 5, got 6: incorrect account sequence"), kind: ErrNonceTooHigh, got: 6, expected: 5, policy: PolicyResyncNonce},
+		{name: "abci code 32 without nonces", err: errors.New("codespace: sdk, code: 32"), kind: ErrNonceTooLow, policy: PolicyResyncNonce},
+		{name: "abci code 5", err: errors.New("codespace sdk code 5: insufficient funds"), kind: ErrInsufficientFunds, policy: PolicySkipAccount},
+		// 限流
+		{name: "http 429", err: rpc.HTTPError{StatusCode: 429, Status: "429 Too Many Requests"}, kind: ErrRateLimited, policy: PolicyBackoff},
+		{name: "wrapped http 429", err: fmt.Errorf("send: %w", rpc.HTTPError{StatusCode: 429}), kind: ErrRateLimited, policy: PolicyBackoff},
+		{name: "rate limit message", err: errors.New("rate limit exceeded"), kind: ErrRateLimited, policy: PolicyBackoff},
+		// 未知错误
+		{name: "http 500", err: rpc.HTTPError{StatusCode: 500, Status: "500 Internal Server Error"}, policy: PolicyFail},
+		{name: "unknown", err: errors.New("execution reverted"), policy: PolicyFail},
+		{name: "unknown abci code", err: errors.New("codespace sdk code 2: tx parse error"), policy: PolicyFail},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Classify(test.err)
+			if err.Error() != test.err.Error() {
+				t.Errorf("Classify(%q) = %q, want the original message", test.err, err)
+			}
+			var classified *Error
+			if test.kind == nil {
+				if errors.As(err, &classified) {
+					t.Errorf("Classify(%q) = %v, want unclassified", test.err, classified.Kind)
+				}
+			} else {
+				if !errors.As(err, &classified) {
+					t.Fatalf("Classify(%q) is not classified, want %v", test.err, test.kind)
+				}
+				if classified.Kind != test.kind {
+					t.Errorf("Classify(%q) = %v, want %v", test.err, classified.Kind, test.kind)
+				}
+				if !errors.Is(err, test.kind) {
+					t.Errorf("errors.Is(Classify(%q), %v) = false", test.err, test.kind)
+				}
+				if classified.Got != test.got || classified.Expected != test.expected {
+					t.Errorf("Classify(%q) got %d expected %d, want got %d expected %d", test.err, classified.Got, classified.Expected, test.got, test.expected)
+				}
+			}
+			if policy := PolicyOf(test.err); policy != test.policy {
+				t.Errorf("PolicyOf(%q) = %s, want %s", test.err, policy, test.policy)
+			}
+		})
+	}
+}
+
+func TestClassifyIsIdempotent(t *testing.T) {
+	err := Classify(errors.New("nonce too low"))
+	if again := Classify(err); again != err {
+		t.Errorf("Classify of a classified error = %v, want the same error", again)
+	}
+	if Classify(nil) != nil {
+		t.Error("Classify(nil) is not nil")
+	}
+	if PolicyOf(nil) != PolicyFail {
+		t.Errorf("PolicyOf(nil) = %s, want %s", PolicyOf(nil), PolicyFail)
+	}
+}
+
+func TestPolicyOfWrappedSentinel(t *testing.T) {
+	err := fmt.Errorf("%w: balance is not enough to pay for gas fee", ErrInsufficientFunds)
+	if policy := PolicyOf(err); policy != PolicySkipAccount {
+		t.Errorf("PolicyOf(%q) = %s, want %s", err, policy, PolicySkipAccount)
+	}
+}
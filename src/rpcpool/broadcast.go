@@ -0,0 +1,92 @@
+package rpcpool
+
+import (
+	"context"
+	"cronos-tools/src/rpcerr"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"time"
+)
+
+// BroadcastResult tells which endpoint accepted a broadcast transaction first.
+type BroadcastResult struct {
+	Hash common.Hash
+	// First 最先接受交易的节点，没有节点接受时为空
+	First string
+	// Latency 从发送到First接受的时间
+	Latency time.Duration
+}
+
+type broadcastReply struct {
+	endpoint *endpoint
+	latency  time.Duration
+	err      error
+}
+
+// Broadcast sends tx to every endpoint in parallel and returns as soon as one accepts it, the
+// other endpoints keep sending in the background. An endpoint answering that it already knows
+// tx counts as accepting it. When no endpoint accepts tx, the error of a node is preferred over
+// connection errors, so the caller sees errors such as nonce too low.
+func (p *Pool) Broadcast(ctx context.Context, tx *types.Transaction) (*BroadcastResult, error) {
+	replies := make(chan broadcastReply, len(p.endpoints))
+	start := time.Now()
+	for _, e := range p.endpoints {
+		go func(e *endpoint) {
+			// 不使用ctx，返回后其他节点继续发送
+			callCtx, cancel := p.Retry.WithTimeout(context.Background())
+			defer cancel()
+			err := e.client.SendTransaction(callCtx, tx)
+			latency := time.Since(start)
+			if IsEndpointError(err) {
+				e.record(latency, err, p.Cooldown)
+			} else {
+				e.record(latency, nil, p.Cooldown)
+			}
+			replies <- broadcastReply{endpoint: e, latency: latency, err: err}
+		}(e)
+	}
+
+	var nodeErr, endpointErr error
+	for range p.endpoints {
+		var reply broadcastReply
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case reply = <-replies:
+		}
+		if reply.err == nil || IsAlreadyKnown(reply.err) {
+			reply.endpoint.mu.Lock()
+			reply.endpoint.firstAccepts++
+			reply.endpoint.mu.Unlock()
+			return &BroadcastResult{Hash: tx.Hash(), First: reply.endpoint.url, Latency: reply.latency}, nil
+		}
+		err := fmt.Errorf("%s: %w", reply.endpoint.url, reply.err)
+		if IsEndpointError(reply.err) {
+			endpointErr = errors.Join(endpointErr, err)
+		} else if nodeErr == nil {
+			nodeErr = reply.err
+		}
+	}
+	if nodeErr != nil {
+		return nil, nodeErr
+	}
+	return nil, endpointErr
+}
+
+// IsAlreadyKnown reports whether err means the node already has the transaction in its mempool.
+func IsAlreadyKnown(err error) bool {
+	return errors.Is(rpcerr.Classify(err), rpcerr.ErrAlreadyKnown)
+}
+
+// sendFanOut 发送交易到所有节点，并记录最先接受的节点
+func (p *Pool) sendFanOut(ctx context.Context, tx *types.Transaction) error {
+	result, err := p.Broadcast(ctx, tx)
+	if err != nil {
+		return err
+	}
+	log.Println("Tx hash:", result.Hash.Hex(), "First accepted by:", result.First, "in", result.Latency)
+	return nil
+}
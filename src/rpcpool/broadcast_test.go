@@ -0,0 +1,102 @@
+package rpcpool
+
+import (
+	"context"
+	"cronos-tools/src/rpcerr"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBroadcast(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup 按a、b、c的顺序设置节点
+		setup []func(n *fakeNode)
+		// first 最先接受交易的节点下标，-1表示没有节点接受
+		first   int
+		wantErr error
+	}{
+		{
+			name: "one accepts",
+			setup: []func(n *fakeNode){
+				func(n *fakeNode) { n.down = true },
+				func(n *fakeNode) { n.sendDelay = 20 * time.Millisecond },
+				func(n *fakeNode) { n.sendErr = "insufficient funds for gas * price + value" },
+			},
+			first: 1,
+		},
+		{
+			name: "already known counts as accepted",
+			setup: []func(n *fakeNode){
+				func(n *fakeNode) { n.down = true },
+				func(n *fakeNode) { n.sendErr = "nonce too low" },
+				func(n *fakeNode) { n.sendErr = "already known"; n.sendDelay = 20 * time.Millisecond },
+			},
+			first: 2,
+		},
+		{
+			name: "node error preferred over endpoint errors",
+			setup: []func(n *fakeNode){
+				func(n *fakeNode) { n.down = true },
+				func(n *fakeNode) { n.sendErr = "nonce too low"; n.sendDelay = 20 * time.Millisecond },
+				func(n *fakeNode) { n.down = true },
+			},
+			first:   -1,
+			wantErr: rpcerr.ErrNonceTooLow,
+		},
+	}
+	for _, test := range tests {
+		nodes := []*fakeNode{newFakeNode(t, 100), newFakeNode(t, 100), newFakeNode(t, 100)}
+		pool := dialNodes(t, nodes...)
+		for i, setup := range test.setup {
+			nodes[i].set(setup)
+		}
+		tx := signedTx(t)
+		result, err := pool.Broadcast(context.Background(), tx)
+		if test.first < 0 {
+			if !errors.Is(rpcerr.Classify(err), test.wantErr) {
+				t.Errorf("%s: Broadcast() = %v, want %v", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Broadcast() = %v, want accepted", test.name, err)
+			continue
+		}
+		if result.Hash != tx.Hash() || result.First != nodes[test.first].server.URL {
+			t.Errorf("%s: Broadcast() = %+v, want first accepted by endpoint %d", test.name, result, test.first)
+		}
+		if accepts := pool.Status()[test.first].FirstAccepts; accepts != 1 {
+			t.Errorf("%s: FirstAccepts = %d, want 1", test.name, accepts)
+		}
+	}
+}
+
+func TestBroadcastAllDown(t *testing.T) {
+	a, b := newFakeNode(t, 100), newFakeNode(t, 100)
+	pool := dialNodes(t, a, b)
+	a.set(func(n *fakeNode) { n.down = true })
+	b.set(func(n *fakeNode) { n.down = true })
+	if _, err := pool.Broadcast(context.Background(), signedTx(t)); err == nil || !IsEndpointError(err) {
+		t.Errorf("Broadcast() with every endpoint down = %v, want an endpoint error", err)
+	}
+}
+
+func TestSendTransactionFanOut(t *testing.T) {
+	a, b := newFakeNode(t, 100), newFakeNode(t, 100)
+	pool := dialNodes(t, a, b)
+	pool.FanOut = true
+	a.set(func(n *fakeNode) { n.sendErr = "already known" })
+	if err := pool.SendTransaction(context.Background(), signedTx(t)); err != nil {
+		t.Fatalf("SendTransaction() = %v, want nil", err)
+	}
+	// 其他节点在后台继续发送
+	deadline := time.Now().Add(time.Second)
+	for a.count("eth_sendRawTransaction")+b.count("eth_sendRawTransaction") < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if a.count("eth_sendRawTransaction") != 1 || b.count("eth_sendRawTransaction") != 1 {
+		t.Errorf("calls = %d %d, want the transaction sent to every endpoint", a.count("eth_sendRawTransaction"), b.count("eth_sendRawTransaction"))
+	}
+}
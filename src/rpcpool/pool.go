@@ -0,0 +1,442 @@
+package rpcpool
+
+import (
+	"context"
+	"cronos-tools/src/retry"
+	"cronos-tools/src/rpcerr"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoEndpoint = errors.New("no rpc endpoint")
+
+// Pool sends each call to the healthiest of several rpc endpoints of the same chain and moves
+// on to the next one when an endpoint fails. An endpoint is healthy when it is not cooling
+// down after a failure, its error rate is below MaxErrorRate and it is at most MaxLag blocks
+// behind the highest endpoint. Errors returned by the node itself, such as nonce too low, are
+// returned to the caller without trying other endpoints. A missing block or receipt is first
+// looked up on the endpoints that are ahead of the one that answered.
+type Pool struct {
+	endpoints []*endpoint
+	// MaxLag 落后最高区块超过该数量的节点视为不健康
+	MaxLag uint64
+	// MaxErrorRate 错误率超过该值的节点视为不健康，0到1之间
+	MaxErrorRate float64
+	// Cooldown 节点调用失败后暂停使用的时间
+	Cooldown time.Duration
+	// HealthInterval 后台检查节点高度和延迟的间隔
+	HealthInterval time.Duration
+	// Retry 所有节点都失败后的重试策略，其中CallTimeout是单个节点一次调用的超时时间，超时后换下一个节点
+	Retry retry.Policy
+	// FanOut 为true时SendTransaction同时发送到所有节点
+	FanOut bool
+}
+
+type endpoint struct {
+	url    string
+	client *ethclient.Client
+
+	mu        sync.Mutex
+	latency   time.Duration
+	height    uint64
+	errorRate float64
+	downUntil time.Time
+	lastError error
+	// firstAccepts 广播时该节点最先接受交易的次数
+	firstAccepts int
+}
+
+// EndpointStatus is a snapshot of the health of one endpoint.
+type EndpointStatus struct {
+	URL       string
+	Latency   time.Duration
+	Height    uint64
+	Lag       uint64
+	ErrorRate float64
+	Healthy   bool
+	LastError error
+	// FirstAccepts 广播时最先接受交易的次数
+	FirstAccepts int
+}
+
+// Dial connects to every url and checks their height once. It fails only when none of the
+// urls can be dialed.
+func Dial(ctx context.Context, urls []string) (*Pool, error) {
+	pool := &Pool{
+		MaxLag:         5,
+		MaxErrorRate:   0.5,
+		Cooldown:       30 * time.Second,
+		HealthInterval: 15 * time.Second,
+		Retry:          retry.Default(),
+	}
+	var dialErrors []string
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			dialErrors = append(dialErrors, fmt.Sprintf("%s: %v", url, err))
+			continue
+		}
+		pool.endpoints = append(pool.endpoints, &endpoint{url: url, client: client})
+	}
+	if len(pool.endpoints) == 0 {
+		if len(dialErrors) == 0 {
+			return nil, ErrNoEndpoint
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNoEndpoint, strings.Join(dialErrors, "; "))
+	}
+	for _, dialError := range dialErrors {
+		log.Println("Can not dial rpc", dialError)
+	}
+	pool.checkHealth(ctx)
+	return pool, nil
+}
+
+// Start checks the health of every endpoint every HealthInterval until ctx is done.
+func (p *Pool) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.HealthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+func (p *Pool) Close() {
+	for _, e := range p.endpoints {
+		e.client.Close()
+	}
+}
+
+// Status returns the health of every endpoint in the order they were given.
+func (p *Pool) Status() []EndpointStatus {
+	best := p.bestHeight()
+	statuses := make([]EndpointStatus, 0, len(p.endpoints))
+	now := time.Now()
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		status := EndpointStatus{
+			URL:          e.url,
+			Latency:      e.latency,
+			Height:       e.height,
+			ErrorRate:    e.errorRate,
+			LastError:    e.lastError,
+			FirstAccepts: e.firstAccepts,
+		}
+		if best > e.height {
+			status.Lag = best - e.height
+		}
+		status.Healthy = p.healthy(e, best, now)
+		e.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// checkHealth 并发查询所有节点的最新高度，并记录延迟
+func (p *Pool) checkHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			callCtx, cancel := p.Retry.WithTimeout(ctx)
+			defer cancel()
+			start := time.Now()
+			height, err := e.client.BlockNumber(callCtx)
+			e.record(time.Since(start), err, p.Cooldown)
+			if err == nil {
+				e.mu.Lock()
+				e.height = height
+				e.downUntil = time.Time{}
+				e.mu.Unlock()
+			}
+		}(e)
+	}
+	wg.Wait()
+}
+
+func (p *Pool) bestHeight() uint64 {
+	var best uint64
+	for _, e := range p.endpoints {
+		e.mu.Lock()
+		if e.height > best {
+			best = e.height
+		}
+		e.mu.Unlock()
+	}
+	return best
+}
+
+// healthy 调用前需要持有e.mu
+func (p *Pool) healthy(e *endpoint, best uint64, now time.Time) bool {
+	if now.Before(e.downUntil) || e.errorRate > p.MaxErrorRate {
+		return false
+	}
+	return e.height+p.MaxLag >= best
+}
+
+// ordered 返回按优先级排序的节点：健康的节点在前，同样健康的按延迟从低到高
+func (p *Pool) ordered() []*endpoint {
+	best := p.bestHeight()
+	now := time.Now()
+	type candidate struct {
+		endpoint *endpoint
+		healthy  bool
+		latency  time.Duration
+	}
+	candidates := make([]candidate, len(p.endpoints))
+	for i, e := range p.endpoints {
+		e.mu.Lock()
+		candidates[i] = candidate{endpoint: e, healthy: p.healthy(e, best, now), latency: e.latency}
+		e.mu.Unlock()
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].healthy != candidates[j].healthy {
+			return candidates[i].healthy
+		}
+		return candidates[i].latency < candidates[j].latency
+	})
+	endpoints := make([]*endpoint, len(candidates))
+	for i, c := range candidates {
+		endpoints[i] = c.endpoint
+	}
+	return endpoints
+}
+
+// record 用指数移动平均更新延迟和错误率，失败的节点暂停使用cooldown
+func (e *endpoint) record(latency time.Duration, err error, cooldown time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = (e.latency*4 + latency) / 5
+	}
+	if err != nil {
+		e.errorRate = e.errorRate*0.8 + 0.2
+		e.lastError = err
+		e.downUntil = time.Now().Add(cooldown)
+		return
+	}
+	e.errorRate = e.errorRate * 0.8
+}
+
+// IsEndpointError reports whether err means the endpoint could not answer, so the call is
+// worth trying on another endpoint. Errors returned by the node in a JSON-RPC response are
+// answers and are not endpoint errors.
+func IsEndpointError(err error) bool {
+	if err == nil || errors.Is(err, ethereum.NotFound) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// 部分节点限流时返回JSON-RPC错误
+		return errors.Is(rpcerr.Classify(err), rpcerr.ErrRateLimited)
+	}
+	return true
+}
+
+// call 按优先级依次在节点上执行fn，直到成功或者返回节点自身的错误，所有节点都失败时按Retry重试
+func call[T any](ctx context.Context, p *Pool, method string, fn func(ctx context.Context, client *ethclient.Client) (T, error)) (T, error) {
+	return callEndpoints(ctx, p, method, false, func(ctx context.Context, e *endpoint) (T, error) {
+		return fn(ctx, e.client)
+	})
+}
+
+// callLatest 与call相同，但节点返回NotFound时继续询问高度更高的节点，落后的节点可能还没有该区块或receipt
+func callLatest[T any](ctx context.Context, p *Pool, method string, fn func(ctx context.Context, client *ethclient.Client) (T, error)) (T, error) {
+	return callEndpoints(ctx, p, method, true, func(ctx context.Context, e *endpoint) (T, error) {
+		return fn(ctx, e.client)
+	})
+}
+
+func callEndpoints[T any](ctx context.Context, p *Pool, method string, latest bool, fn func(ctx context.Context, e *endpoint) (T, error)) (T, error) {
+	policy := p.Retry
+	policy.Retryable = IsEndpointError
+	// 超时只限制单个节点的调用
+	policy.CallTimeout = 0
+	return retry.Value(ctx, policy, "call rpc "+method, func(ctx context.Context) (T, error) {
+		return failover(ctx, p, method, latest, fn)
+	})
+}
+
+// failover 按优先级依次在节点上执行fn，直到成功或者返回节点自身的错误。latest为true时，
+// NotFound只有在没有比回答的节点更高的节点时才返回
+func failover[T any](ctx context.Context, p *Pool, method string, latest bool, fn func(ctx context.Context, e *endpoint) (T, error)) (T, error) {
+	var result T
+	var err error
+	var notFound error
+	var notFoundHeight uint64
+	for _, e := range p.ordered() {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if notFound != nil && e.currentHeight() <= notFoundHeight {
+			continue
+		}
+		callCtx, cancel := p.Retry.WithTimeout(ctx)
+		start := time.Now()
+		result, err = fn(callCtx, e)
+		cancel()
+		if !IsEndpointError(err) || ctx.Err() != nil {
+			e.record(time.Since(start), nil, p.Cooldown)
+			if latest && errors.Is(err, ethereum.NotFound) && ctx.Err() == nil {
+				if notFound == nil || e.currentHeight() > notFoundHeight {
+					notFound, notFoundHeight = err, e.currentHeight()
+				}
+				continue
+			}
+			return result, err
+		}
+		e.record(time.Since(start), err, p.Cooldown)
+		if len(p.endpoints) > 1 {
+			log.Println("Rpc", e.url, method, "failed, switch to next endpoint", err)
+		}
+	}
+	if notFound != nil {
+		var zero T
+		return zero, notFound
+	}
+	return result, err
+}
+
+// currentHeight 返回最近一次查询到的节点高度
+func (e *endpoint) currentHeight() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.height
+}
+
+// observeHeight 记录调用中得到的更高的节点高度
+func (e *endpoint) observeHeight(height uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if height > e.height {
+		e.height = height
+	}
+}
+
+func (p *Pool) NetworkID(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, "net_version", func(ctx context.Context, c *ethclient.Client) (*big.Int, error) {
+		return c.NetworkID(ctx)
+	})
+}
+
+func (p *Pool) ChainID(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, "eth_chainId", func(ctx context.Context, c *ethclient.Client) (*big.Int, error) {
+		return c.ChainID(ctx)
+	})
+}
+
+// BlockNumber returns the height of the healthiest endpoint and remembers it, so later lookups
+// of that block are sent to endpoints that have it.
+func (p *Pool) BlockNumber(ctx context.Context) (uint64, error) {
+	return callEndpoints(ctx, p, "eth_blockNumber", false, func(ctx context.Context, e *endpoint) (uint64, error) {
+		height, err := e.client.BlockNumber(ctx)
+		if err == nil {
+			e.observeHeight(height)
+		}
+		return height, err
+	})
+}
+
+func (p *Pool) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return callLatest(ctx, p, "eth_getBlockByNumber", func(ctx context.Context, c *ethclient.Client) (*types.Block, error) {
+		return c.BlockByNumber(ctx, number)
+	})
+}
+
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return callLatest(ctx, p, "eth_getBlockByNumber", func(ctx context.Context, c *ethclient.Client) (*types.Header, error) {
+		return c.HeaderByNumber(ctx, number)
+	})
+}
+
+func (p *Pool) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return call(ctx, p, "eth_getBalance", func(ctx context.Context, c *ethclient.Client) (*big.Int, error) {
+		return c.BalanceAt(ctx, account, blockNumber)
+	})
+}
+
+func (p *Pool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return call(ctx, p, "eth_getTransactionCount", func(ctx context.Context, c *ethclient.Client) (uint64, error) {
+		return c.NonceAt(ctx, account, blockNumber)
+	})
+}
+
+func (p *Pool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return call(ctx, p, "eth_getTransactionCount", func(ctx context.Context, c *ethclient.Client) (uint64, error) {
+		return c.PendingNonceAt(ctx, account)
+	})
+}
+
+func (p *Pool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, "eth_gasPrice", func(ctx context.Context, c *ethclient.Client) (*big.Int, error) {
+		return c.SuggestGasPrice(ctx)
+	})
+}
+
+func (p *Pool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, "eth_maxPriorityFeePerGas", func(ctx context.Context, c *ethclient.Client) (*big.Int, error) {
+		return c.SuggestGasTipCap(ctx)
+	})
+}
+
+func (p *Pool) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return call(ctx, p, "eth_estimateGas", func(ctx context.Context, c *ethclient.Client) (uint64, error) {
+		return c.EstimateGas(ctx, msg)
+	})
+}
+
+func (p *Pool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return callLatest(ctx, p, "eth_getTransactionReceipt", func(ctx context.Context, c *ethclient.Client) (*types.Receipt, error) {
+		return c.TransactionReceipt(ctx, txHash)
+	})
+}
+
+// TransactionByHash returns the transaction with the given hash and whether it is still pending.
+func (p *Pool) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	type found struct {
+		tx        *types.Transaction
+		isPending bool
+	}
+	result, err := callLatest(ctx, p, "eth_getTransactionByHash", func(ctx context.Context, c *ethclient.Client) (found, error) {
+		tx, isPending, err := c.TransactionByHash(ctx, hash)
+		return found{tx: tx, isPending: isPending}, err
+	})
+	return result.tx, result.isPending, err
+}
+
+// SendTransaction sends tx to the healthiest endpoint, or to every endpoint when FanOut is set.
+// When an endpoint fails after it may have accepted tx, the next endpoint can answer already
+// known, which the sender handles.
+func (p *Pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if p.FanOut && len(p.endpoints) > 1 {
+		return p.sendFanOut(ctx, tx)
+	}
+	_, err := call(ctx, p, "eth_sendRawTransaction", func(ctx context.Context, c *ethclient.Client) (struct{}, error) {
+		return struct{}{}, c.SendTransaction(ctx, tx)
+	})
+	return err
+}
@@ -0,0 +1,209 @@
+package rpcpool
+
+import (
+	"context"
+	"cronos-tools/src/retry"
+	"cronos-tools/src/rpcerr"
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNode 一个只实现测试所需方法的JSON-RPC节点
+type fakeNode struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	height uint64
+	// down 为true时返回HTTP 503
+	down     bool
+	receipts map[common.Hash]*types.Receipt
+	// sendErr 不为空时eth_sendRawTransaction返回该JSON-RPC错误
+	sendErr string
+	// sendDelay eth_sendRawTransaction返回前的等待时间
+	sendDelay time.Duration
+	calls     map[string]int
+}
+
+func newFakeNode(t *testing.T, height uint64) *fakeNode {
+	node := &fakeNode{height: height, receipts: make(map[common.Hash]*types.Receipt), calls: make(map[string]int)}
+	node.server = httptest.NewServer(http.HandlerFunc(node.serve))
+	t.Cleanup(node.server.Close)
+	return node
+}
+
+func (n *fakeNode) serve(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n.mu.Lock()
+	n.calls[request.Method]++
+	down, height, sendErr, sendDelay := n.down, n.height, n.sendErr, n.sendDelay
+	n.mu.Unlock()
+	if down {
+		http.Error(w, "node is down", http.StatusServiceUnavailable)
+		return
+	}
+
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": request.ID}
+	switch request.Method {
+	case "eth_blockNumber":
+		response["result"] = hexutil.Uint64(height)
+	case "net_version":
+		response["result"] = "25"
+	case "eth_getTransactionReceipt":
+		var hash common.Hash
+		json.Unmarshal(request.Params[0], &hash)
+		n.mu.Lock()
+		response["result"] = n.receipts[hash]
+		n.mu.Unlock()
+	case "eth_sendRawTransaction":
+		time.Sleep(sendDelay)
+		if sendErr != "" {
+			response["error"] = map[string]interface{}{"code": -32000, "message": sendErr}
+			break
+		}
+		var raw hexutil.Bytes
+		json.Unmarshal(request.Params[0], &raw)
+		tx := new(types.Transaction)
+		tx.UnmarshalBinary(raw)
+		response["result"] = tx.Hash()
+	default:
+		response["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (n *fakeNode) set(f func(n *fakeNode)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f(n)
+}
+
+func (n *fakeNode) count(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+// dialNodes 连接所有节点，并按给出的顺序设置延迟，使节点的优先级与顺序一致
+func dialNodes(t *testing.T, nodes ...*fakeNode) *Pool {
+	urls := make([]string, len(nodes))
+	for i, node := range nodes {
+		urls[i] = node.server.URL
+	}
+	pool, err := Dial(context.Background(), urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	pool.Retry = retry.Policy{MaxAttempts: 1, CallTimeout: 5 * time.Second}
+	for i, e := range pool.endpoints {
+		e.latency = time.Duration(i+1) * time.Millisecond
+	}
+	return pool
+}
+
+func signedTx(t *testing.T) *types.Transaction {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x01")
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(25)), &types.LegacyTx{
+		To:       &to,
+		Gas:      21000,
+		GasPrice: big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestFailover(t *testing.T) {
+	a, b, c := newFakeNode(t, 100), newFakeNode(t, 100), newFakeNode(t, 100)
+	pool := dialNodes(t, a, b, c)
+	a.set(func(n *fakeNode) { n.down = true })
+	b.set(func(n *fakeNode) { n.down = true })
+
+	id, err := pool.NetworkID(context.Background())
+	if err != nil || id.Int64() != 25 {
+		t.Fatalf("NetworkID() = %v %v, want 25", id, err)
+	}
+	if a.count("net_version") != 1 || b.count("net_version") != 1 || c.count("net_version") != 1 {
+		t.Errorf("calls = %d %d %d, want each endpoint tried once in order", a.count("net_version"), b.count("net_version"), c.count("net_version"))
+	}
+	// 失败的节点在冷却期间排在后面
+	if _, err := pool.NetworkID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if a.count("net_version") != 1 || b.count("net_version") != 1 || c.count("net_version") != 2 {
+		t.Errorf("calls after failover = %d %d %d, want only the healthy endpoint", a.count("net_version"), b.count("net_version"), c.count("net_version"))
+	}
+	statuses := pool.Status()
+	if statuses[0].Healthy || statuses[1].Healthy || !statuses[2].Healthy {
+		t.Errorf("Status() = %+v, want only the last endpoint healthy", statuses)
+	}
+
+	c.set(func(n *fakeNode) { n.down = true })
+	if _, err := pool.NetworkID(context.Background()); err == nil {
+		t.Error("NetworkID() with every endpoint down = nil, want an error")
+	}
+}
+
+func TestNodeErrorIsNotFailedOver(t *testing.T) {
+	a, b := newFakeNode(t, 100), newFakeNode(t, 100)
+	pool := dialNodes(t, a, b)
+	a.set(func(n *fakeNode) { n.sendErr = "nonce too low" })
+
+	err := pool.SendTransaction(context.Background(), signedTx(t))
+	if !errors.Is(rpcerr.Classify(err), rpcerr.ErrNonceTooLow) {
+		t.Errorf("SendTransaction() = %v, want nonce too low", err)
+	}
+	if b.count("eth_sendRawTransaction") != 0 {
+		t.Error("an error of the node was retried on another endpoint")
+	}
+}
+
+func TestNotFoundLooksAhead(t *testing.T) {
+	// a最快但落后，b更高并且有receipt
+	a, b, c := newFakeNode(t, 100), newFakeNode(t, 103), newFakeNode(t, 101)
+	hash := common.HexToHash("0x01")
+	b.receipts[hash] = &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}, BlockNumber: big.NewInt(103)}
+	pool := dialNodes(t, a, b, c)
+
+	receipt, err := pool.TransactionReceipt(context.Background(), hash)
+	if err != nil || receipt.TxHash != hash {
+		t.Fatalf("TransactionReceipt() = %v %v, want the receipt of the endpoint that is ahead", receipt, err)
+	}
+	if a.count("eth_getTransactionReceipt") != 1 || b.count("eth_getTransactionReceipt") != 1 {
+		t.Errorf("calls = %d %d, want the lagging endpoint then the one ahead", a.count("eth_getTransactionReceipt"), b.count("eth_getTransactionReceipt"))
+	}
+
+	// 没有节点有该receipt时返回NotFound，不高于已回答节点的节点不再询问
+	_, err = pool.TransactionReceipt(context.Background(), common.HexToHash("0x02"))
+	if !errors.Is(err, ethereum.NotFound) {
+		t.Errorf("TransactionReceipt() of an unknown tx = %v, want NotFound", err)
+	}
+	if a.count("eth_getTransactionReceipt") != 2 || b.count("eth_getTransactionReceipt") != 2 || c.count("eth_getTransactionReceipt") != 0 {
+		t.Errorf("calls = %d %d %d, want the endpoint below the highest answer skipped", a.count("eth_getTransactionReceipt"), b.count("eth_getTransactionReceipt"), c.count("eth_getTransactionReceipt"))
+	}
+}
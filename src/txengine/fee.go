@@ -0,0 +1,133 @@
+package txengine
+
+import (
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log"
+	"math/big"
+	"time"
+)
+
+// FeeConfig controls how the Sender prices its transactions.
+type FeeConfig struct {
+	// DynamicFee 为true时发送EIP-1559交易，否则发送legacy交易
+	DynamicFee bool
+	// MaxFee EIP-1559的gas fee cap，为空时取2倍base fee加上tip
+	MaxFee *big.Int
+	// MaxPriorityFee EIP-1559的tip，为空时使用SuggestGasTipCap
+	MaxPriorityFee *big.Int
+	// GasPriceMultiplier 作用于节点建议的gas price、tip和fee cap，不作用于手动指定的值
+	GasPriceMultiplier decimal.Decimal
+	// FeeCeiling 单位gas价格的上限，超过时暂停发送直到价格回落
+	FeeCeiling *big.Int
+	// CeilingPollInterval 暂停期间重新查询价格的间隔
+	CeilingPollInterval time.Duration
+}
+
+// DefaultFeeConfig sends legacy transactions at the suggested gas price.
+func DefaultFeeConfig() FeeConfig {
+	return FeeConfig{
+		GasPriceMultiplier:  decimal.NewFromInt(1),
+		CeilingPollInterval: 10 * time.Second,
+	}
+}
+
+// Fees are the prices put into one transaction. GasPrice is set for legacy transactions,
+// GasFeeCap and GasTipCap for dynamic fee transactions.
+type Fees struct {
+	GasPrice  *big.Int
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+	// expected 预计实际支付的单位gas价格，用于与FeeCeiling比较
+	expected *big.Int
+}
+
+// MaxPricePerGas is the highest price per gas the transaction can be charged.
+func (f *Fees) MaxPricePerGas() *big.Int {
+	if f.GasFeeCap != nil {
+		return f.GasFeeCap
+	}
+	return f.GasPrice
+}
+
+// bump 返回各项价格提高12.5%后的Fees
+func (f *Fees) bump() *Fees {
+	bumped := &Fees{}
+	if f.GasFeeCap != nil {
+		bumped.GasFeeCap = bumpPrice(f.GasFeeCap, nil)
+		bumped.GasTipCap = bumpPrice(f.GasTipCap, nil)
+	} else {
+		bumped.GasPrice = bumpPrice(f.GasPrice, nil)
+	}
+	bumped.expected = bumped.MaxPricePerGas()
+	return bumped
+}
+
+func (s *Sender) multiply(value *big.Int) *big.Int {
+	if s.Fees.GasPriceMultiplier.IsZero() {
+		return value
+	}
+	return decimal.NewFromBigInt(value, 0).Mul(s.Fees.GasPriceMultiplier).BigInt()
+}
+
+// SuggestFees queries the node for the current prices and applies the FeeConfig.
+func (s *Sender) SuggestFees(ctx context.Context) (*Fees, error) {
+	if !s.Fees.DynamicFee {
+		gasPrice, err := s.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+		gasPrice = s.multiply(gasPrice)
+		return &Fees{GasPrice: gasPrice, expected: gasPrice}, nil
+	}
+
+	// 获取最新区块的base fee
+	header, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	baseFee := header.BaseFee
+	if baseFee == nil {
+		return nil, fmt.Errorf("latest header has no base fee, use legacy transactions instead")
+	}
+
+	tip := s.Fees.MaxPriorityFee
+	if tip == nil {
+		if tip, err = s.client.SuggestGasTipCap(ctx); err != nil {
+			return nil, err
+		}
+		tip = s.multiply(tip)
+	}
+	feeCap := s.Fees.MaxFee
+	if feeCap == nil {
+		feeCap = s.multiply(new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tip))
+	}
+	if feeCap.Cmp(tip) < 0 {
+		return nil, fmt.Errorf("max fee %s is lower than max priority fee %s", feeCap, tip)
+	}
+	expected := new(big.Int).Add(baseFee, tip)
+	if expected.Cmp(feeCap) > 0 {
+		expected = feeCap
+	}
+	return &Fees{GasFeeCap: feeCap, GasTipCap: tip, expected: expected}, nil
+}
+
+// WaitFees returns the current fees, pausing while they are above the fee ceiling.
+func (s *Sender) WaitFees(ctx context.Context) (*Fees, error) {
+	for {
+		fees, err := s.SuggestFees(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if s.Fees.FeeCeiling == nil || fees.expected.Cmp(s.Fees.FeeCeiling) <= 0 {
+			return fees, nil
+		}
+		log.Println("Address:", s.address.Hex(), "Gas price", fees.expected, "is above fee ceiling", s.Fees.FeeCeiling, ", pause sending")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.Fees.CeilingPollInterval):
+		}
+	}
+}
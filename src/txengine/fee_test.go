@@ -0,0 +1,175 @@
+package txengine
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFeeSender(t *testing.T, client *fakeClient, config FeeConfig) *Sender {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := NewSender(client, big.NewInt(25), key)
+	sender.Fees = config
+	return sender
+}
+
+func TestSuggestFees(t *testing.T) {
+	dynamic := func(multiplier int64, maxFee int64, maxPriorityFee int64) FeeConfig {
+		config := DefaultFeeConfig()
+		config.DynamicFee = true
+		config.GasPriceMultiplier = decimal.NewFromInt(multiplier)
+		if maxFee > 0 {
+			config.MaxFee = big.NewInt(maxFee)
+		}
+		if maxPriorityFee > 0 {
+			config.MaxPriorityFee = big.NewInt(maxPriorityFee)
+		}
+		return config
+	}
+	legacy := DefaultFeeConfig()
+	legacyMultiplied := DefaultFeeConfig()
+	legacyMultiplied.GasPriceMultiplier = decimal.RequireFromString("1.5")
+
+	tests := []struct {
+		name     string
+		config   FeeConfig
+		baseFee  int64
+		gasPrice *big.Int
+		// want 依次是gas price或fee cap、tip和预计价格
+		want    [3]int64
+		wantErr string
+	}{
+		{name: "legacy", config: legacy, gasPrice: big.NewInt(100), want: [3]int64{100, 0, 100}},
+		{name: "legacy multiplied", config: legacyMultiplied, gasPrice: big.NewInt(100), want: [3]int64{150, 0, 150}},
+		{name: "dynamic", config: dynamic(1, 0, 0), baseFee: 100, want: [3]int64{210, 10, 110}},
+		{name: "dynamic multiplied", config: dynamic(2, 0, 0), baseFee: 100, want: [3]int64{440, 20, 120}},
+		{name: "manual tip is not multiplied", config: dynamic(2, 0, 5), baseFee: 100, want: [3]int64{410, 5, 105}},
+		{name: "manual fee cap", config: dynamic(2, 150, 0), baseFee: 100, want: [3]int64{150, 20, 120}},
+		{name: "expected price capped by fee cap", config: dynamic(1, 105, 0), baseFee: 100, want: [3]int64{105, 10, 105}},
+		{name: "fee cap below tip", config: dynamic(1, 5, 0), baseFee: 100, wantErr: "lower than max priority fee"},
+		{name: "no base fee", config: dynamic(1, 0, 0), wantErr: "no base fee"},
+	}
+	for _, test := range tests {
+		client := &fakeClient{tipCap: big.NewInt(10)}
+		if test.gasPrice != nil {
+			client.gasPrices = []*big.Int{test.gasPrice}
+		}
+		if test.baseFee > 0 {
+			client.baseFee = big.NewInt(test.baseFee)
+		}
+		fees, err := newFeeSender(t, client, test.config).SuggestFees(context.Background())
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: SuggestFees error = %v, want %q", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: SuggestFees error = %v", test.name, err)
+			continue
+		}
+		tip := int64(0)
+		if fees.GasTipCap != nil {
+			tip = fees.GasTipCap.Int64()
+		}
+		got := [3]int64{fees.MaxPricePerGas().Int64(), tip, fees.expected.Int64()}
+		if got != test.want {
+			t.Errorf("%s: SuggestFees = %v, want %v", test.name, got, test.want)
+		}
+		if test.config.DynamicFee != (fees.GasFeeCap != nil) || test.config.DynamicFee == (fees.GasPrice != nil) {
+			t.Errorf("%s: SuggestFees = %+v, want only the fields of its transaction type", test.name, fees)
+		}
+	}
+}
+
+func TestWaitFeesCeiling(t *testing.T) {
+	withCeiling := func(config FeeConfig, ceiling int64) FeeConfig {
+		config.FeeCeiling = big.NewInt(ceiling)
+		config.CeilingPollInterval = time.Millisecond
+		return config
+	}
+	dynamic := DefaultFeeConfig()
+	dynamic.DynamicFee = true
+
+	tests := []struct {
+		name      string
+		config    FeeConfig
+		gasPrices []int64
+		baseFee   int64
+		want      int64
+	}{
+		{name: "at the ceiling", config: withCeiling(DefaultFeeConfig(), 100), gasPrices: []int64{100}, want: 100},
+		{name: "pause until the price falls", config: withCeiling(DefaultFeeConfig(), 100), gasPrices: []int64{150, 120, 90}, want: 90},
+		// 比较预计支付的base fee加tip，而不是fee cap
+		{name: "dynamic fee cap above the ceiling", config: withCeiling(dynamic, 150), baseFee: 100, want: 210},
+		{name: "no ceiling", config: DefaultFeeConfig(), gasPrices: []int64{1000}, want: 1000},
+	}
+	for _, test := range tests {
+		client := &fakeClient{tipCap: big.NewInt(10)}
+		if test.baseFee > 0 {
+			client.baseFee = big.NewInt(test.baseFee)
+		}
+		for _, gasPrice := range test.gasPrices {
+			client.gasPrices = append(client.gasPrices, big.NewInt(gasPrice))
+		}
+		fees, err := newFeeSender(t, client, test.config).WaitFees(context.Background())
+		if err != nil || fees.MaxPricePerGas().Int64() != test.want {
+			t.Errorf("%s: WaitFees = %v %v, want %d", test.name, fees, err, test.want)
+			continue
+		}
+		if len(client.gasPrices) > 1 {
+			t.Errorf("%s: %d gas prices left unused, want all of them queried", test.name, len(client.gasPrices)-1)
+		}
+	}
+
+	client := &fakeClient{gasPrices: []*big.Int{big.NewInt(150)}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := newFeeSender(t, client, withCeiling(DefaultFeeConfig(), 100)).WaitFees(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitFees above the ceiling = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestSendValueBumpCeiling(t *testing.T) {
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tests := []struct {
+		name      string
+		ceiling   int64
+		wantSends int
+	}{
+		// 100提高12.5%后超过上限，不再重发
+		{name: "bump above the ceiling", ceiling: 110, wantSends: 1},
+		{name: "bumps within the ceiling", ceiling: 1000, wantSends: 3},
+	}
+	for _, test := range tests {
+		client := &fakeClient{
+			gasPrices: []*big.Int{big.NewInt(100)},
+			balance:   big.NewInt(1e18),
+			sendErr:   errors.New("transaction underpriced"),
+		}
+		config := DefaultFeeConfig()
+		config.FeeCeiling = big.NewInt(test.ceiling)
+		sender := newFeeSender(t, client, config)
+		sender.GasLimit = 21000
+		sender.Retry.MaxAttempts = 3
+		_, err := sender.Send(context.Background(), to, nil)
+		if err == nil {
+			t.Errorf("%s: Send = nil, want underpriced", test.name)
+		}
+		if client.sends != test.wantSends {
+			t.Errorf("%s: sent %d times, want %d", test.name, client.sends, test.wantSends)
+		}
+		if test.wantSends == 1 && !strings.Contains(err.Error(), "fee ceiling") {
+			t.Errorf("%s: Send error = %v, want the fee ceiling", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package txengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"log"
+	"math"
+	"math/big"
+)
+
+// ErrGasLimitExceeded is returned when a transaction needs more gas than GasConfig.MaxGas.
+var ErrGasLimitExceeded = errors.New("gas limit exceeded")
+
+// GasConfig controls how the Sender sizes the gas limit of its transactions.
+type GasConfig struct {
+	// Margin 估算结果上增加的比例，0.1表示多10%，不作用于不带数据的普通转账
+	Margin float64
+	// MaxGas 单笔交易gas limit的上限，超过时拒绝发送，0表示不限制
+	MaxGas uint64
+	// CrossCheck 为true时同时调用节点的EstimateGas，取两者中较大的值
+	CrossCheck bool
+}
+
+// DefaultGasConfig adds a 10% margin to payloads and cross-checks with the node.
+func DefaultGasConfig() GasConfig {
+	return GasConfig{Margin: 0.1, CrossCheck: true}
+}
+
+// IntrinsicGas is the gas a call to an account without code costs: 21000 plus 4 for each zero
+// and 16 for each non-zero byte of payload.
+func IntrinsicGas(payload []byte) (uint64, error) {
+	return core.IntrinsicGas(payload, nil, false, true, true, true)
+}
+
+// EstimateGas returns the gas limit of a transaction from the Sender carrying value and payload.
+// The limit is the intrinsic gas of payload, raised to the estimate of the node when CrossCheck
+// is set and the node needs more, plus Margin when there is a payload or the node needs more
+// than the intrinsic gas. A failing node estimate is logged and ignored.
+func (s *Sender) EstimateGas(ctx context.Context, to common.Address, value *big.Int, payload []byte) (uint64, error) {
+	gas, err := IntrinsicGas(payload)
+	if err != nil {
+		return 0, err
+	}
+	exact := len(payload) == 0
+	if s.Gas.CrossCheck {
+		estimated, err := s.client.EstimateGas(ctx, ethereum.CallMsg{From: s.address, To: &to, Value: value, Data: payload})
+		if err != nil {
+			log.Println("Address:", s.address.Hex(), "Can not estimate gas, use intrinsic gas", gas, err)
+		} else if estimated > gas {
+			log.Println("Address:", s.address.Hex(), "Node estimates", estimated, "gas, intrinsic gas is", gas)
+			gas = estimated
+			exact = false
+		}
+	}
+	if !exact && s.Gas.Margin > 0 {
+		gas = uint64(math.Ceil(float64(gas) * (1 + s.Gas.Margin)))
+	}
+	if s.Gas.MaxGas > 0 && gas > s.Gas.MaxGas {
+		return 0, fmt.Errorf("%w: payload of %d bytes needs %d gas, max %d", ErrGasLimitExceeded, len(payload), gas, s.Gas.MaxGas)
+	}
+	return gas, nil
+}
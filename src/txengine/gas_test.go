@@ -0,0 +1,142 @@
+package txengine
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+)
+
+// fakeClient 只实现测试用到的调用，没有设置的调用返回错误
+type fakeClient struct {
+	estimate    uint64
+	estimateErr error
+	estimates   int
+	// gasPrices 依次返回的gas price，用完后一直返回最后一个
+	gasPrices []*big.Int
+	tipCap    *big.Int
+	baseFee   *big.Int
+	balance   *big.Int
+	sendErr   error
+	sends     int
+}
+
+var errNotImplemented = errors.New("not implemented")
+
+func (c *fakeClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (c *fakeClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if len(c.gasPrices) == 0 {
+		return nil, errNotImplemented
+	}
+	gasPrice := c.gasPrices[0]
+	if len(c.gasPrices) > 1 {
+		c.gasPrices = c.gasPrices[1:]
+	}
+	return gasPrice, nil
+}
+
+func (c *fakeClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	if c.tipCap == nil {
+		return nil, errNotImplemented
+	}
+	return c.tipCap, nil
+}
+
+func (c *fakeClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(1), BaseFee: c.baseFee}, nil
+}
+
+func (c *fakeClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	if c.balance == nil {
+		return nil, errNotImplemented
+	}
+	return c.balance, nil
+}
+
+func (c *fakeClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	c.estimates++
+	return c.estimate, c.estimateErr
+}
+
+func (c *fakeClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.sends++
+	return c.sendErr
+}
+
+func TestIntrinsicGas(t *testing.T) {
+	tests := []struct {
+		payload []byte
+		want    uint64
+	}{
+		{nil, 21000},
+		{[]byte{0, 0}, 21008},
+		{[]byte("data:,"), 21096},
+		{[]byte{0, 1}, 21020},
+	}
+	for _, test := range tests {
+		got, err := IntrinsicGas(test.payload)
+		if err != nil || got != test.want {
+			t.Errorf("IntrinsicGas(%x) = %d %v, want %d", test.payload, got, err, test.want)
+		}
+	}
+}
+
+func TestEstimateGas(t *testing.T) {
+	payload := []byte(`data:,{"p":"crc-20","op":"mint","tick":"cros","amt":"1000"}`)
+	intrinsic, err := IntrinsicGas(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		payload     []byte
+		config      GasConfig
+		estimate    uint64
+		estimateErr error
+		want        uint64
+		err         error
+	}{
+		{name: "plain transfer", config: DefaultGasConfig(), estimate: 21000, want: 21000},
+		{name: "plain transfer to a contract", config: DefaultGasConfig(), estimate: 30000, want: 33000},
+		{name: "plain transfer without margin", config: GasConfig{CrossCheck: true}, estimate: 30000, want: 30000},
+		{name: "payload below intrinsic", payload: payload, config: DefaultGasConfig(), estimate: 21000, want: intrinsic + (intrinsic+9)/10},
+		{name: "payload above intrinsic", payload: payload, config: DefaultGasConfig(), estimate: 40000, want: 44000},
+		{name: "node error", payload: payload, config: DefaultGasConfig(), estimateErr: errors.New("execution reverted"), want: intrinsic + (intrinsic+9)/10},
+		{name: "node error on plain transfer", config: DefaultGasConfig(), estimateErr: errors.New("execution reverted"), want: 21000},
+		{name: "no cross check", payload: payload, config: GasConfig{Margin: 0.1}, estimate: 40000, want: intrinsic + (intrinsic+9)/10},
+		{name: "max gas", payload: payload, config: GasConfig{CrossCheck: true, MaxGas: 30000}, estimate: 40000, err: ErrGasLimitExceeded},
+		{name: "within max gas", payload: payload, config: GasConfig{CrossCheck: true, MaxGas: 40000}, estimate: 40000, want: 40000},
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &fakeClient{estimate: test.estimate, estimateErr: test.estimateErr}
+			sender := NewSender(client, big.NewInt(25), key)
+			sender.Gas = test.config
+			got, err := sender.EstimateGas(context.Background(), to, big.NewInt(0), test.payload)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Errorf("EstimateGas error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil || got != test.want {
+				t.Errorf("EstimateGas = %d %v, want %d", got, err, test.want)
+			}
+			if wantCalls := map[bool]int{true: 1, false: 0}[test.config.CrossCheck]; client.estimates != wantCalls {
+				t.Errorf("node estimated %d times, want %d", client.estimates, wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package txengine
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NonceClient is the part of ethclient.Client the NonceManager needs.
+type NonceClient interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// NonceManager hands out nonces from a local counter per address so that several
+// transactions of one account can be in flight at the same time. It only goes back
+// to the chain when an account is first used, when the in-flight window is full and
+// when Resync is called after the node rejected a nonce.
+type NonceManager struct {
+	client       NonceClient
+	maxInFlight  int
+	pollInterval time.Duration
+
+	mu       sync.Mutex
+	accounts map[common.Address]*accountNonces
+}
+
+type accountNonces struct {
+	mu     sync.Mutex
+	synced bool
+	// next 下一个未使用过的nonce
+	next uint64
+	// reserved 已分配但还没有发送结果的nonce
+	reserved map[uint64]struct{}
+	// inFlight 已发送但还没有上链的nonce
+	inFlight map[uint64]struct{}
+	// gaps 分配后没有发送成功、需要重新填补的nonce，从小到大排列
+	gaps []uint64
+}
+
+// NewNonceManager creates a NonceManager that keeps at most maxInFlight unconfirmed
+// transactions per address.
+func NewNonceManager(client NonceClient, maxInFlight int) *NonceManager {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	return &NonceManager{
+		client:       client,
+		maxInFlight:  maxInFlight,
+		pollInterval: time.Second,
+		accounts:     make(map[common.Address]*accountNonces),
+	}
+}
+
+func (m *NonceManager) account(address common.Address) *accountNonces {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	account, ok := m.accounts[address]
+	if !ok {
+		account = &accountNonces{
+			reserved: make(map[uint64]struct{}),
+			inFlight: make(map[uint64]struct{}),
+		}
+		m.accounts[address] = account
+	}
+	return account
+}
+
+// Acquire returns the nonce for the next transaction of address. Nonces left behind by
+// failed sends are handed out first. When the in-flight window is full it waits until
+// the chain has confirmed some of them.
+func (m *NonceManager) Acquire(ctx context.Context, address common.Address) (uint64, error) {
+	account := m.account(address)
+	for {
+		account.mu.Lock()
+		if !account.synced {
+			if err := m.resync(ctx, address, account); err != nil {
+				account.mu.Unlock()
+				return 0, err
+			}
+		}
+		if len(account.gaps) > 0 {
+			nonce := account.gaps[0]
+			account.gaps = account.gaps[1:]
+			account.reserved[nonce] = struct{}{}
+			account.mu.Unlock()
+			return nonce, nil
+		}
+		if len(account.reserved)+len(account.inFlight) < m.maxInFlight {
+			nonce := account.next
+			account.next++
+			account.reserved[nonce] = struct{}{}
+			account.mu.Unlock()
+			return nonce, nil
+		}
+		// 窗口已满，查询链上已确认的nonce释放窗口
+		confirmed, err := m.client.NonceAt(ctx, address, nil)
+		if err != nil {
+			account.mu.Unlock()
+			return 0, err
+		}
+		freed := account.confirm(confirmed)
+		account.mu.Unlock()
+		if freed {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(m.pollInterval):
+		}
+	}
+}
+
+// MarkSent records that the transaction using nonce was accepted by the node.
+func (m *NonceManager) MarkSent(address common.Address, nonce uint64) {
+	account := m.account(address)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	delete(account.reserved, nonce)
+	account.inFlight[nonce] = struct{}{}
+}
+
+// Release gives back a nonce whose transaction was not accepted, so the next Acquire
+// fills the gap instead of leaving later transactions stuck behind it.
+func (m *NonceManager) Release(address common.Address, nonce uint64) {
+	account := m.account(address)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	delete(account.reserved, nonce)
+	account.addGap(nonce)
+}
+
+// Resync reloads the nonce of address from the chain. It should be called when the node
+// reports the nonce is too low or the sequence is invalid. In-flight nonces the node no
+// longer has in its mempool are turned into gaps and sent again.
+func (m *NonceManager) Resync(ctx context.Context, address common.Address) error {
+	account := m.account(address)
+	account.mu.Lock()
+	defer account.mu.Unlock()
+	return m.resync(ctx, address, account)
+}
+
+func (m *NonceManager) resync(ctx context.Context, address common.Address, account *accountNonces) error {
+	pending, err := m.client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return err
+	}
+	confirmed, err := m.client.NonceAt(ctx, address, nil)
+	if err != nil {
+		return err
+	}
+	account.confirm(confirmed)
+	if !account.synced || account.next < pending {
+		account.next = pending
+	}
+	account.synced = true
+
+	// 节点pending nonce之后的已发送交易已经丢失，需要重新发送
+	for nonce := range account.inFlight {
+		if nonce >= pending {
+			delete(account.inFlight, nonce)
+			account.addGap(nonce)
+		}
+	}
+	// 已被其他交易使用的nonce不再需要填补
+	gaps := account.gaps[:0]
+	for _, nonce := range account.gaps {
+		if nonce >= pending {
+			gaps = append(gaps, nonce)
+		}
+	}
+	account.gaps = gaps
+	return nil
+}
+
+// confirm drops every in-flight nonce below the confirmed nonce and reports whether any was dropped.
+func (a *accountNonces) confirm(confirmed uint64) bool {
+	freed := false
+	for nonce := range a.inFlight {
+		if nonce < confirmed {
+			delete(a.inFlight, nonce)
+			freed = true
+		}
+	}
+	return freed
+}
+
+func (a *accountNonces) addGap(nonce uint64) {
+	if nonce >= a.next {
+		return
+	}
+	for _, gap := range a.gaps {
+		if gap == nonce {
+			return
+		}
+	}
+	a.gaps = append(a.gaps, nonce)
+	sort.Slice(a.gaps, func(i, j int) bool { return a.gaps[i] < a.gaps[j] })
+	// 末尾的空洞直接回退next
+	for len(a.gaps) > 0 && a.gaps[len(a.gaps)-1] == a.next-1 {
+		a.gaps = a.gaps[:len(a.gaps)-1]
+		a.next--
+	}
+}
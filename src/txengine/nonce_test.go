@@ -0,0 +1,141 @@
+package txengine
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNonces 返回可修改的pending和已确认nonce
+type fakeNonces struct {
+	mu        sync.Mutex
+	pending   uint64
+	confirmed uint64
+}
+
+func (f *fakeNonces) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pending, nil
+}
+
+func (f *fakeNonces) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.confirmed, nil
+}
+
+func (f *fakeNonces) set(pending uint64, confirmed uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending, f.confirmed = pending, confirmed
+}
+
+var testAddress = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+
+func acquire(t *testing.T, m *NonceManager) uint64 {
+	t.Helper()
+	nonce, err := m.Acquire(context.Background(), testAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nonce
+}
+
+func checkAcquire(t *testing.T, m *NonceManager, want ...uint64) {
+	t.Helper()
+	for _, w := range want {
+		if got := acquire(t, m); got != w {
+			t.Fatalf("Acquire() = %d, want %d", got, w)
+		}
+	}
+}
+
+func TestNonceManagerSequence(t *testing.T) {
+	m := NewNonceManager(&fakeNonces{pending: 5, confirmed: 5}, 10)
+	checkAcquire(t, m, 5, 6, 7)
+}
+
+func TestNonceManagerFillsGaps(t *testing.T) {
+	m := NewNonceManager(&fakeNonces{pending: 5, confirmed: 5}, 10)
+	checkAcquire(t, m, 5, 6, 7, 8)
+	m.MarkSent(testAddress, 5)
+	m.MarkSent(testAddress, 8)
+	m.Release(testAddress, 7)
+	m.Release(testAddress, 6)
+	// 先填补空洞，从小到大
+	checkAcquire(t, m, 6, 7, 9)
+
+	// 释放最后分配的nonce时回退，不留空洞
+	m.Release(testAddress, 9)
+	checkAcquire(t, m, 9)
+	m.Release(testAddress, 9)
+	m.Release(testAddress, 9)
+	checkAcquire(t, m, 9, 10)
+}
+
+func TestNonceManagerResync(t *testing.T) {
+	client := &fakeNonces{pending: 5, confirmed: 5}
+	m := NewNonceManager(client, 10)
+	checkAcquire(t, m, 5, 6, 7, 8, 9)
+	for _, nonce := range []uint64{5, 6, 8} {
+		m.MarkSent(testAddress, nonce)
+	}
+	m.Release(testAddress, 7)
+	m.Release(testAddress, 9)
+
+	// 节点只收到了5和6，8已丢失需要重发，7仍是空洞
+	client.set(7, 5)
+	if err := m.Resync(context.Background(), testAddress); err != nil {
+		t.Fatal(err)
+	}
+	checkAcquire(t, m, 7, 8, 9)
+
+	// 其他程序用掉了账户的nonce，发送失败后释放再同步，空洞不再填补
+	client.set(20, 20)
+	m.Release(testAddress, 8)
+	if err := m.Resync(context.Background(), testAddress); err != nil {
+		t.Fatal(err)
+	}
+	checkAcquire(t, m, 20)
+}
+
+func TestNonceManagerResyncKeepsInFlight(t *testing.T) {
+	client := &fakeNonces{pending: 5, confirmed: 5}
+	m := NewNonceManager(client, 10)
+	checkAcquire(t, m, 5, 6, 7)
+	for _, nonce := range []uint64{5, 6, 7} {
+		m.MarkSent(testAddress, nonce)
+	}
+	// 节点已收到所有交易时同步不改变已发送的nonce
+	client.set(8, 5)
+	if err := m.Resync(context.Background(), testAddress); err != nil {
+		t.Fatal(err)
+	}
+	checkAcquire(t, m, 8)
+}
+
+func TestNonceManagerWindow(t *testing.T) {
+	client := &fakeNonces{pending: 0, confirmed: 0}
+	m := NewNonceManager(client, 2)
+	m.pollInterval = time.Millisecond
+	checkAcquire(t, m, 0, 1)
+	m.MarkSent(testAddress, 0)
+	m.MarkSent(testAddress, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := m.Acquire(ctx, testAddress); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire() with a full window error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		client.set(2, 1)
+	}()
+	checkAcquire(t, m, 2)
+}
@@ -0,0 +1,314 @@
+package txengine
+
+import (
+	"context"
+	"cronos-tools/src/retry"
+	"cronos-tools/src/rpcerr"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+	"log"
+	"math/big"
+	"time"
+)
+
+// ErrInsufficientBalance 本地检查发现余额不足以支付gas fee，与节点返回的insufficient funds按同样的策略处理
+var ErrInsufficientBalance = fmt.Errorf("%w: balance is not enough to pay for gas fee", rpcerr.ErrInsufficientFunds)
+
+// ErrFeeCeiling is returned by Bump when the replacement would pay more per gas than FeeConfig.FeeCeiling.
+var ErrFeeCeiling = errors.New("above fee ceiling")
+
+// Client is the part of ethclient.Client the engine needs. Calls are expected to retry network
+// failures themselves, as rpcpool.Pool does.
+type Client interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+type Status string
+
+const (
+	StatusSent   Status = "sent"
+	StatusFailed Status = "failed"
+)
+
+// Result describes a transaction the Sender built and tried to broadcast.
+type Result struct {
+	Hash  common.Hash
+	Nonce uint64
+	// GasPrice is the gas price of a legacy transaction or the fee cap of a dynamic fee transaction
+	GasPrice  *big.Int
+	GasTipCap *big.Int
+	// Fee is the maximum fee the transaction can cost, gas limit * gas price
+	Fee    *big.Int
+	Status Status
+	// Tx is the signed transaction, used to track and rebroadcast it
+	Tx *types.Transaction
+}
+
+// Sender signs and sends transactions from a single account.
+type Sender struct {
+	client  Client
+	chainID *big.Int
+	key     *ecdsa.PrivateKey
+	address common.Address
+
+	// GasLimit 为0时每笔交易按Gas估算
+	GasLimit uint64
+	Gas      GasConfig
+	// Retry 节点拒绝交易后重发的次数和等待时间
+	Retry retry.Policy
+	Fees  FeeConfig
+	// Nonces 不为空时使用本地nonce管理，否则每次发送前查询PendingNonceAt
+	Nonces *NonceManager
+	// OnSigned 不为空时在每笔交易和替换交易签名后、发送前调用，用于在发送前记录交易
+	OnSigned func(tx *types.Transaction)
+}
+
+func NewSender(client Client, chainID *big.Int, key *ecdsa.PrivateKey) *Sender {
+	return &Sender{
+		client:  client,
+		chainID: chainID,
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+		Gas:     DefaultGasConfig(),
+		Retry:   retry.Default(),
+		Fees:    DefaultFeeConfig(),
+	}
+}
+
+func (s *Sender) Address() common.Address {
+	return s.address
+}
+
+// Send builds a legacy or dynamic fee transaction carrying payload to the given address, signs it and sends it.
+// The returned Result is not nil once the transaction has been signed, even if sending failed.
+func (s *Sender) Send(ctx context.Context, to common.Address, payload []byte) (*Result, error) {
+	return s.SendValue(ctx, to, decimal.Zero.BigInt(), payload)
+}
+
+// SendValue is Send with an amount of native coin transferred along. When the node rejects the
+// transaction as underpriced the fees are raised by 12.5% and it is sent again, up to
+// Retry.MaxAttempts times in total and never above the fee ceiling.
+func (s *Sender) SendValue(ctx context.Context, to common.Address, value *big.Int, payload []byte) (*Result, error) {
+	// 获取当前的gas价格
+	fees, err := s.WaitFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for bumps := 0; ; bumps++ {
+		result, err := s.SendWithFees(ctx, to, value, payload, fees)
+		if err == nil || rpcerr.PolicyOf(err) != rpcerr.PolicyBumpFee || bumps+1 >= s.Retry.MaxAttempts {
+			return result, err
+		}
+		fees = fees.bump()
+		if s.Fees.FeeCeiling != nil && fees.MaxPricePerGas().Cmp(s.Fees.FeeCeiling) > 0 {
+			return result, fmt.Errorf("can not raise gas price above fee ceiling %s: %w", s.Fees.FeeCeiling, err)
+		}
+		log.Println("Address:", s.address.Hex(), "Transaction underpriced, raise gas price to", fees.MaxPricePerGas())
+	}
+}
+
+// SendWithFees is SendValue with fees fixed by the caller instead of queried from the node.
+// Errors of the node are classified by rpcerr and recovered according to their policy: a
+// transaction the node already has counts as sent, nonce errors resync the nonce and rate
+// limits or a full mempool back off as Retry says, up to Retry.MaxAttempts sends in total.
+// Other errors are returned and can be matched with errors.Is against the rpcerr sentinels.
+// When GasLimit is 0 the gas limit is estimated from payload first.
+func (s *Sender) SendWithFees(ctx context.Context, to common.Address, value *big.Int, payload []byte, fees *Fees) (*Result, error) {
+	gasLimit := s.GasLimit
+	if gasLimit == 0 {
+		var err error
+		if gasLimit, err = s.EstimateGas(ctx, to, value, payload); err != nil {
+			return nil, err
+		}
+	}
+	// 检查当前账户的native coin余额是否足够支付gas fee
+	balance, err := s.client.BalanceAt(ctx, s.address, nil)
+	if err != nil {
+		return nil, err
+	}
+	gasFee := decimal.NewFromBigInt(fees.MaxPricePerGas(), 0).Mul(decimal.NewFromInt(int64(gasLimit))).BigInt()
+	if balance.Cmp(new(big.Int).Add(gasFee, value)) < 0 {
+		return nil, ErrInsufficientBalance
+	}
+
+	for attempt := 0; ; attempt++ {
+		result, err := s.sendOnce(ctx, to, value, payload, fees, gasLimit, gasFee)
+		if err == nil || result == nil {
+			return result, err
+		}
+		policy := rpcerr.PolicyOf(err)
+		if policy == rpcerr.PolicyTreatAsSent {
+			// 相同交易已在mempool中，nonce仍然有效
+			result.Status = StatusSent
+			if s.Nonces != nil {
+				s.Nonces.MarkSent(s.address, result.Nonce)
+			}
+			return result, nil
+		}
+		s.releaseNonce(result.Nonce)
+		if attempt+1 >= s.Retry.MaxAttempts {
+			return result, err
+		}
+		delay := s.Retry.Delay(attempt)
+		switch policy {
+		case rpcerr.PolicyResyncNonce:
+			log.Println("Address:", s.address.Hex(), "Nonce", result.Nonce, "rejected, resync nonce and retry after", delay, err)
+			if s.Nonces != nil {
+				if resyncErr := s.Nonces.Resync(ctx, s.address); resyncErr != nil {
+					log.Println("Can not resync nonce", resyncErr)
+				}
+			}
+		case rpcerr.PolicyBackoff:
+			log.Println("Address:", s.address.Hex(), "Node is busy, retry after", delay, err)
+		default:
+			return result, err
+		}
+		// 节点持续拒绝时不能立即重发，避免空转
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// sendOnce 用新的nonce构造、签名并发送一次交易，发送失败时不释放nonce，由调用方按错误类型处理
+func (s *Sender) sendOnce(ctx context.Context, to common.Address, value *big.Int, payload []byte, fees *Fees, gasLimit uint64, gasFee *big.Int) (*Result, error) {
+	// 获取当前账户的nonce
+	nonce, err := s.acquireNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 构造交易
+	var tx *types.Transaction
+	if fees.GasFeeCap != nil {
+		tx = types.NewTx(&types.DynamicFeeTx{
+			ChainID:   s.chainID,
+			Nonce:     nonce,
+			To:        &to,
+			Value:     value,
+			Gas:       gasLimit,
+			GasFeeCap: fees.GasFeeCap,
+			GasTipCap: fees.GasTipCap,
+			Data:      payload,
+		})
+	} else {
+		tx = types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			To:       &to,
+			Value:    value,
+			Gas:      gasLimit,
+			GasPrice: fees.GasPrice,
+			Data:     payload,
+		})
+	}
+	// 签名交易
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(s.chainID), s.key)
+	if err != nil {
+		s.releaseNonce(nonce)
+		return nil, fmt.Errorf("can not sign transaction: %w", err)
+	}
+	result := &Result{
+		Hash:      signedTx.Hash(),
+		Nonce:     nonce,
+		GasPrice:  fees.MaxPricePerGas(),
+		GasTipCap: fees.GasTipCap,
+		Fee:       gasFee,
+		Status:    StatusSent,
+		Tx:        signedTx,
+	}
+	if s.OnSigned != nil {
+		s.OnSigned(signedTx)
+	}
+	// 发送交易
+	if err := s.client.SendTransaction(ctx, signedTx); err != nil {
+		result.Status = StatusFailed
+		return result, rpcerr.Classify(err)
+	}
+	if s.Nonces != nil {
+		s.Nonces.MarkSent(s.address, nonce)
+	}
+	return result, nil
+}
+
+// Bump re-signs tx with the same nonce and fees at least 12.5% higher, so the node accepts
+// it as a replacement, and sends it. A replacement above the fee ceiling is not sent and
+// ErrFeeCeiling is returned, a lower price would be rejected by the node as underpriced.
+func (s *Sender) Bump(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	fees, err := s.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var replacement *types.Transaction
+	if tx.Type() == types.DynamicFeeTxType {
+		replacement = types.NewTx(&types.DynamicFeeTx{
+			ChainID:   s.chainID,
+			Nonce:     tx.Nonce(),
+			To:        tx.To(),
+			Value:     tx.Value(),
+			Gas:       tx.Gas(),
+			GasFeeCap: bumpPrice(tx.GasFeeCap(), fees.GasFeeCap),
+			GasTipCap: bumpPrice(tx.GasTipCap(), fees.GasTipCap),
+			Data:      tx.Data(),
+		})
+	} else {
+		replacement = types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Gas:      tx.Gas(),
+			GasPrice: bumpPrice(tx.GasPrice(), fees.MaxPricePerGas()),
+			Data:     tx.Data(),
+		})
+	}
+	if s.Fees.FeeCeiling != nil && replacement.GasFeeCap().Cmp(s.Fees.FeeCeiling) > 0 {
+		return nil, fmt.Errorf("%w: replacement of %s needs %s per gas, fee ceiling %s", ErrFeeCeiling, tx.Hash().Hex(), replacement.GasFeeCap(), s.Fees.FeeCeiling)
+	}
+	signedTx, err := types.SignTx(replacement, types.LatestSignerForChainID(s.chainID), s.key)
+	if err != nil {
+		return nil, fmt.Errorf("can not sign transaction: %w", err)
+	}
+	if s.OnSigned != nil {
+		s.OnSigned(signedTx)
+	}
+	if err := s.client.SendTransaction(ctx, signedTx); err != nil {
+		return nil, rpcerr.Classify(err)
+	}
+	return signedTx, nil
+}
+
+// bumpPrice returns the larger of old raised by 12.5% and the currently suggested price.
+func bumpPrice(old *big.Int, suggested *big.Int) *big.Int {
+	bumped := new(big.Int).Div(new(big.Int).Mul(old, big.NewInt(1125)), big.NewInt(1000))
+	bumped.Add(bumped, big.NewInt(1))
+	if suggested != nil && suggested.Cmp(bumped) > 0 {
+		return suggested
+	}
+	return bumped
+}
+
+func (s *Sender) acquireNonce(ctx context.Context) (uint64, error) {
+	if s.Nonces != nil {
+		return s.Nonces.Acquire(ctx, s.address)
+	}
+	return s.client.PendingNonceAt(ctx, s.address)
+}
+
+func (s *Sender) releaseNonce(nonce uint64) {
+	if s.Nonces != nil {
+		s.Nonces.Release(s.address, nonce)
+	}
+}
@@ -0,0 +1,316 @@
+package txengine
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// ReceiptClient is the part of ethclient.Client the Tracker needs.
+type ReceiptClient interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+// BumpFunc re-signs tx with higher fees and sends it, returning the replacement.
+type BumpFunc func(ctx context.Context, tx *types.Transaction) (*types.Transaction, error)
+
+type ConfirmStatus string
+
+const (
+	ConfirmStatusConfirmed ConfirmStatus = "confirmed"
+	ConfirmStatusReverted  ConfirmStatus = "reverted"
+	ConfirmStatusReplaced  ConfirmStatus = "replaced"
+	ConfirmStatusDropped   ConfirmStatus = "dropped"
+)
+
+// Confirmation is the final state of a tracked transaction. Hash is the hash that was
+// included, which differs from the original hash when the transaction was bumped.
+type Confirmation struct {
+	Account      common.Address
+	Nonce        uint64
+	Hash         common.Hash
+	BlockNumber  uint64
+	GasUsed      uint64
+	Status       ConfirmStatus
+	Rebroadcasts int
+	Bumps        int
+}
+
+// AccountConfirmations counts the final states of the transactions of one account.
+type AccountConfirmations struct {
+	Confirmed int
+	Reverted  int
+	Replaced  int
+	Dropped   int
+}
+
+type trackedTx struct {
+	account common.Address
+	nonce   uint64
+	// txs 原始交易以及之后提高gas价格的替换交易
+	txs          []*types.Transaction
+	bump         BumpFunc
+	lastSent     time.Time
+	rebroadcasts int
+}
+
+// Tracker polls receipts of sent transactions until each one is included, replaced by
+// another transaction with the same nonce or given up as dropped. A transaction that is
+// not included within DropTimeout is broadcast again, and bumped once MaxRebroadcasts is
+// reached.
+type Tracker struct {
+	client          ReceiptClient
+	PollInterval    time.Duration
+	DropTimeout     time.Duration
+	MaxRebroadcasts int
+	MaxBumps        int
+	// OnConfirm 每笔交易有最终结果时调用
+	OnConfirm func(*Confirmation)
+
+	mu      sync.Mutex
+	pending []*trackedTx
+	done    []*Confirmation
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func NewTracker(client ReceiptClient) *Tracker {
+	return &Tracker{
+		client:          client,
+		PollInterval:    3 * time.Second,
+		DropTimeout:     60 * time.Second,
+		MaxRebroadcasts: 2,
+		MaxBumps:        3,
+	}
+}
+
+// Track starts watching tx. bump may be nil, in which case the transaction is only rebroadcast.
+func (t *Tracker) Track(account common.Address, tx *types.Transaction, bump BumpFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, &trackedTx{
+		account:  account,
+		nonce:    tx.Nonce(),
+		txs:      []*types.Transaction{tx},
+		bump:     bump,
+		lastSent: time.Now(),
+	})
+}
+
+// Start polls in the background until Wait is called.
+func (t *Tracker) Start(ctx context.Context) {
+	t.stop = make(chan struct{})
+	t.stopped = make(chan struct{})
+	go func() {
+		defer close(t.stopped)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.stop:
+				return
+			case <-time.After(t.PollInterval):
+				t.poll(ctx)
+			}
+		}
+	}()
+}
+
+// Wait stops background polling and keeps polling until every tracked transaction has a
+// final state or ctx is done. It returns the confirmations collected so far.
+func (t *Tracker) Wait(ctx context.Context) []*Confirmation {
+	if t.stop != nil {
+		close(t.stop)
+		<-t.stopped
+		t.stop = nil
+	}
+	for t.Pending() > 0 {
+		select {
+		case <-ctx.Done():
+			return t.Confirmations()
+		case <-time.After(t.PollInterval):
+			t.poll(ctx)
+		}
+	}
+	return t.Confirmations()
+}
+
+func (t *Tracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
+
+func (t *Tracker) Confirmations() []*Confirmation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Confirmation(nil), t.done...)
+}
+
+// Summary counts the final states per account.
+func (t *Tracker) Summary() map[common.Address]*AccountConfirmations {
+	summary := make(map[common.Address]*AccountConfirmations)
+	for _, confirmation := range t.Confirmations() {
+		counts, ok := summary[confirmation.Account]
+		if !ok {
+			counts = &AccountConfirmations{}
+			summary[confirmation.Account] = counts
+		}
+		switch confirmation.Status {
+		case ConfirmStatusConfirmed:
+			counts.Confirmed++
+		case ConfirmStatusReverted:
+			counts.Reverted++
+		case ConfirmStatusReplaced:
+			counts.Replaced++
+		case ConfirmStatusDropped:
+			counts.Dropped++
+		}
+	}
+	return summary
+}
+
+func (t *Tracker) poll(ctx context.Context) {
+	t.mu.Lock()
+	pending := append([]*trackedTx(nil), t.pending...)
+	t.mu.Unlock()
+
+	// 每个账户只查询一次链上nonce
+	nonces := make(map[common.Address]uint64)
+	var finished []*Confirmation
+	resolved := make(map[*trackedTx]bool)
+	for _, tracked := range pending {
+		confirmation, err := t.check(ctx, tracked, nonces)
+		if err != nil {
+			log.Println("Can not check transaction", tracked.txs[len(tracked.txs)-1].Hash().Hex(), err)
+			continue
+		}
+		if confirmation != nil {
+			finished = append(finished, confirmation)
+			resolved[tracked] = true
+		}
+	}
+
+	t.mu.Lock()
+	remaining := t.pending[:0]
+	for _, tracked := range t.pending {
+		if !resolved[tracked] {
+			remaining = append(remaining, tracked)
+		}
+	}
+	t.pending = remaining
+	t.done = append(t.done, finished...)
+	t.mu.Unlock()
+
+	if t.OnConfirm != nil {
+		for _, confirmation := range finished {
+			t.OnConfirm(confirmation)
+		}
+	}
+}
+
+// check returns the final state of tracked, or nil if it is still pending.
+func (t *Tracker) check(ctx context.Context, tracked *trackedTx, nonces map[common.Address]uint64) (*Confirmation, error) {
+	confirmation := &Confirmation{
+		Account:      tracked.account,
+		Nonce:        tracked.nonce,
+		Rebroadcasts: tracked.rebroadcasts,
+		Bumps:        len(tracked.txs) - 1,
+	}
+	found, err := t.findReceipt(ctx, tracked, confirmation)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return confirmation, nil
+	}
+
+	latest := tracked.txs[len(tracked.txs)-1]
+	confirmation.Hash = latest.Hash()
+	nonce, ok := nonces[tracked.account]
+	if !ok {
+		nonce, err = t.client.NonceAt(ctx, tracked.account, nil)
+		if err != nil {
+			return nil, err
+		}
+		nonces[tracked.account] = nonce
+	}
+	if nonce > tracked.nonce {
+		// 交易可能在查询receipt之后、查询nonce之前上链，再查一次receipt才能确定nonce被其他交易使用
+		found, err := t.findReceipt(ctx, tracked, confirmation)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return confirmation, nil
+		}
+		confirmation.Status = ConfirmStatusReplaced
+		return confirmation, nil
+	}
+	if time.Since(tracked.lastSent) < t.DropTimeout {
+		return nil, nil
+	}
+
+	// 超时未上链，重新广播或者提高gas价格
+	if tracked.rebroadcasts < t.MaxRebroadcasts {
+		tracked.rebroadcasts++
+		tracked.lastSent = time.Now()
+		if err := t.client.SendTransaction(ctx, latest); err != nil {
+			log.Println("Rebroadcast transaction", latest.Hash().Hex(), err)
+		} else {
+			log.Println("Rebroadcast transaction", latest.Hash().Hex())
+		}
+		return nil, nil
+	}
+	if tracked.bump != nil && len(tracked.txs)-1 < t.MaxBumps {
+		tracked.lastSent = time.Now()
+		bumped, err := tracked.bump(ctx, latest)
+		if errors.Is(err, ErrFeeCeiling) {
+			// 不再提高gas价格，继续重新广播当前交易直到次数用完
+			log.Println("Stop bumping transaction", latest.Hash().Hex(), err)
+			tracked.bump = nil
+			tracked.rebroadcasts = 0
+			return nil, nil
+		}
+		if err != nil {
+			log.Println("Can not bump transaction", latest.Hash().Hex(), err)
+			return nil, nil
+		}
+		tracked.txs = append(tracked.txs, bumped)
+		tracked.rebroadcasts = 0
+		log.Println("Bump transaction", latest.Hash().Hex(), "to", bumped.Hash().Hex())
+		return nil, nil
+	}
+	confirmation.Status = ConfirmStatusDropped
+	return confirmation, nil
+}
+
+// findReceipt 查询原始交易和替换交易的receipt，任意一笔上链即可，找到时填充confirmation
+func (t *Tracker) findReceipt(ctx context.Context, tracked *trackedTx, confirmation *Confirmation) (bool, error) {
+	for _, tx := range tracked.txs {
+		receipt, err := t.client.TransactionReceipt(ctx, tx.Hash())
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		confirmation.Hash = tx.Hash()
+		confirmation.BlockNumber = receipt.BlockNumber.Uint64()
+		confirmation.GasUsed = receipt.GasUsed
+		confirmation.Status = ConfirmStatusConfirmed
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			confirmation.Status = ConfirmStatusReverted
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,210 @@
+package txengine
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// fakeChain 按hash保存回执，记录重新广播的交易
+type fakeChain struct {
+	receipts map[common.Hash]*types.Receipt
+	nonce    uint64
+	sent     []common.Hash
+	// onNonceAt 不为空时在查询nonce时调用，模拟交易在两次查询之间上链
+	onNonceAt func()
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{receipts: make(map[common.Hash]*types.Receipt)}
+}
+
+func (c *fakeChain) TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+	receipt, ok := c.receipts[hash]
+	if !ok {
+		return nil, ethereum.NotFound
+	}
+	return receipt, nil
+}
+
+func (c *fakeChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	if c.onNonceAt != nil {
+		c.onNonceAt()
+	}
+	return c.nonce, nil
+}
+
+func (c *fakeChain) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.sent = append(c.sent, tx.Hash())
+	return nil
+}
+
+// mine 让tx上链并推进账户nonce
+func (c *fakeChain) mine(tx *types.Transaction) {
+	c.receipts[tx.Hash()] = &types.Receipt{TxHash: tx.Hash(), Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(10), GasUsed: 21000}
+	c.nonce = tx.Nonce() + 1
+}
+
+func signTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, gasPrice int64) *types.Transaction {
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(25)), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       &testAddress,
+		Gas:      21000,
+		GasPrice: big.NewInt(gasPrice),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+// newTestTracker 每次poll都认为交易已超时，poll由测试直接调用
+func newTestTracker(chain *fakeChain) *Tracker {
+	tracker := NewTracker(chain)
+	tracker.DropTimeout = 0
+	return tracker
+}
+
+func TestTrackerBumpedTxConfirmed(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	original := signTx(t, key, 0, 100)
+	bumped := signTx(t, key, 0, 113)
+
+	for _, race := range []bool{false, true} {
+		chain := newFakeChain()
+		tracker := newTestTracker(chain)
+		tracker.MaxRebroadcasts = 0
+		var confirmations []*Confirmation
+		tracker.OnConfirm = func(confirmation *Confirmation) {
+			confirmations = append(confirmations, confirmation)
+		}
+		tracker.Track(address, original, func(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+			return bumped, nil
+		})
+
+		tracker.poll(context.Background())
+		if tracker.Pending() != 1 {
+			t.Fatalf("pending = %d after bumping, want 1", tracker.Pending())
+		}
+		if race {
+			// 替换交易在查询receipt之后、查询nonce之前上链
+			chain.onNonceAt = func() { chain.mine(bumped) }
+		} else {
+			chain.mine(bumped)
+		}
+		tracker.poll(context.Background())
+		if len(confirmations) != 1 {
+			t.Fatalf("race %v: got %d confirmations, want 1", race, len(confirmations))
+		}
+		confirmation := confirmations[0]
+		if confirmation.Status != ConfirmStatusConfirmed || confirmation.Hash != bumped.Hash() || confirmation.Bumps != 1 || confirmation.BlockNumber != 10 {
+			t.Errorf("race %v: confirmation = %+v, want the bumped transaction confirmed", race, confirmation)
+		}
+	}
+}
+
+func TestTrackerReplaced(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := newFakeChain()
+	tracker := newTestTracker(chain)
+	tx := signTx(t, key, 0, 100)
+	tracker.Track(crypto.PubkeyToAddress(key.PublicKey), tx, nil)
+	// nonce被另一笔交易使用
+	chain.nonce = 1
+	tracker.poll(context.Background())
+	confirmations := tracker.Confirmations()
+	if len(confirmations) != 1 || confirmations[0].Status != ConfirmStatusReplaced || confirmations[0].Hash != tx.Hash() {
+		t.Errorf("confirmations = %+v, want the transaction replaced", confirmations)
+	}
+}
+
+func TestTrackerRebroadcastDropped(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := newFakeChain()
+	tracker := newTestTracker(chain)
+	tracker.MaxRebroadcasts = 1
+	tx := signTx(t, key, 0, 100)
+	tracker.Track(crypto.PubkeyToAddress(key.PublicKey), tx, nil)
+
+	tracker.poll(context.Background())
+	if len(chain.sent) != 1 || chain.sent[0] != tx.Hash() || tracker.Pending() != 1 {
+		t.Fatalf("sent = %v, pending = %d, want the transaction rebroadcast and still pending", chain.sent, tracker.Pending())
+	}
+	// 重新广播次数用完并且不能提高gas价格
+	tracker.poll(context.Background())
+	confirmations := tracker.Confirmations()
+	if len(confirmations) != 1 || confirmations[0].Status != ConfirmStatusDropped || confirmations[0].Rebroadcasts != 1 {
+		t.Errorf("confirmations = %+v, want the transaction dropped after 1 rebroadcast", confirmations)
+	}
+	if len(chain.sent) != 1 {
+		t.Errorf("sent %d times, want 1", len(chain.sent))
+	}
+}
+
+func TestTrackerWaitsBeforeRebroadcast(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain := newFakeChain()
+	tracker := NewTracker(chain)
+	tracker.DropTimeout = time.Hour
+	tracker.Track(crypto.PubkeyToAddress(key.PublicKey), signTx(t, key, 0, 100), nil)
+	tracker.poll(context.Background())
+	if len(chain.sent) != 0 || tracker.Pending() != 1 {
+		t.Errorf("sent = %v, pending = %d, want nothing rebroadcast before the drop timeout", chain.sent, tracker.Pending())
+	}
+}
+
+func TestTrackerBumpFeeCeiling(t *testing.T) {
+	for _, ceiling := range []int64{110, 113} {
+		client := &fakeClient{gasPrices: []*big.Int{big.NewInt(100)}}
+		config := DefaultFeeConfig()
+		config.FeeCeiling = big.NewInt(ceiling)
+		sender := newFeeSender(t, client, config)
+		chain := newFakeChain()
+		tracker := newTestTracker(chain)
+		tracker.MaxRebroadcasts = 1
+		tx := signTx(t, sender.key, 0, 100)
+		tracker.Track(sender.address, tx, sender.Bump)
+
+		// 第一次重新广播，第二次提高gas价格
+		tracker.poll(context.Background())
+		tracker.poll(context.Background())
+		if ceiling == 113 {
+			if client.sends != 1 || len(tracker.Confirmations()) != 0 {
+				t.Errorf("ceiling %d: sent %d replacements, want the bumped price 113 sent", ceiling, client.sends)
+			}
+			continue
+		}
+		if client.sends != 0 {
+			t.Errorf("ceiling %d: sent %d replacements above the ceiling, want 0", ceiling, client.sends)
+		}
+		// 停止提高gas价格后继续重新广播原交易，次数用完后报告丢弃
+		tracker.poll(context.Background())
+		if len(chain.sent) != 2 || chain.sent[1] != tx.Hash() {
+			t.Errorf("ceiling %d: rebroadcast %v, want the original transaction twice", ceiling, chain.sent)
+		}
+		tracker.poll(context.Background())
+		confirmations := tracker.Confirmations()
+		if len(confirmations) != 1 || confirmations[0].Status != ConfirmStatusDropped || confirmations[0].Bumps != 0 {
+			t.Errorf("ceiling %d: confirmations = %+v, want the transaction dropped without bumps", ceiling, confirmations)
+		}
+	}
+}
@@ -2,27 +2,90 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/tyler-smith/go-bip32"
 	"github.com/tyler-smith/go-bip39"
+	"strconv"
+	"strings"
 )
 
-func GetPrivateKey(mnemonic string, accountIndex uint) *ecdsa.PrivateKey {
-	seed := bip39.NewSeed(mnemonic, "") // 可以提供密码短语
-	masterKey, _ := bip32.NewMasterKey(seed)
-	// 44'
-	purposeKey, _ := masterKey.NewChildKey(0x8000002C)
-	// 60'
-	coinTypeKey, _ := purposeKey.NewChildKey(0x8000003C)
-	// 0'
-	accountKey, _ := coinTypeKey.NewChildKey(0x80000000)
-	// 0
-	changeKey, _ := accountKey.NewChildKey(0)
-	// addressIndex
-	addressKey, _ := changeKey.NewChildKey(uint32(accountIndex))
-	privateKey, _ := crypto.ToECDSA(addressKey.Key)
-	return privateKey
+const (
+	// IndexPlaceholder 派生路径模板中账户序号的占位符
+	IndexPlaceholder = "{index}"
+	// DefaultHDPath bip-44标准路径 m/44'/60'/0'/0/i
+	DefaultHDPath = "m/44'/60'/0'/0/" + IndexPlaceholder
+	// LedgerLiveHDPath Ledger Live使用的路径 m/44'/60'/i'/0/0
+	LedgerLiveHDPath = "m/44'/60'/" + IndexPlaceholder + "'/0/0"
+)
+
+// HDPath returns the derivation path of the account at index. template must start with m/
+// and contain {index} once, e.g. m/44'/60'/1'/0/{index} or m/44'/60'/{index}'/0/0.
+func HDPath(template string, index uint) (string, error) {
+	if !strings.HasPrefix(template, "m/") {
+		return "", fmt.Errorf("hd path %q must start with m/", template)
+	}
+	if strings.Count(template, IndexPlaceholder) != 1 {
+		return "", fmt.Errorf("hd path %q must contain %s exactly once", template, IndexPlaceholder)
+	}
+	return strings.Replace(template, IndexPlaceholder, strconv.FormatUint(uint64(index), 10), 1), nil
+}
+
+// DerivePrivateKey derives the key of the account at index from a BIP-39 mnemonic, an
+// optional BIP-39 passphrase and a path template as accepted by HDPath. Use MasterKey and
+// DeriveChildKey to derive many accounts from the same mnemonic.
+func DerivePrivateKey(mnemonic string, passphrase string, template string, index uint) (*ecdsa.PrivateKey, error) {
+	master, err := MasterKey(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return DeriveChildKey(master, template, index)
+}
+
+// MasterKey checks the words and checksum of a BIP-39 mnemonic and returns the BIP-32 master
+// key of its seed. Computing the seed runs PBKDF2, so it is done once per mnemonic.
+func MasterKey(mnemonic string, passphrase string) (*bip32.Key, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return bip32.NewMasterKey(seed)
+}
+
+// DeriveChildKey derives the key of the account at index from a master key along a path
+// template as accepted by HDPath.
+func DeriveChildKey(master *bip32.Key, template string, index uint) (*ecdsa.PrivateKey, error) {
+	path, err := HDPath(template, index)
+	if err != nil {
+		return nil, err
+	}
+	derivationPath, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	key := master
+	for _, childIndex := range derivationPath {
+		key, err = key.NewChildKey(childIndex)
+		if err != nil {
+			return nil, fmt.Errorf("can not derive %s: %w", path, err)
+		}
+	}
+	privateKey, err := crypto.ToECDSA(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	if privateKey == nil {
+		return nil, errors.New("derived an empty private key")
+	}
+	return privateKey, nil
+}
+
+// GetPrivateKey derives the key at m/44'/60'/0'/0/accountIndex without a BIP-39 passphrase.
+func GetPrivateKey(mnemonic string, accountIndex uint) (*ecdsa.PrivateKey, error) {
+	return DerivePrivateKey(mnemonic, "", DefaultHDPath, accountIndex)
 }
 
 func GetPublicKey(privateKey *ecdsa.PrivateKey) *ecdsa.PublicKey {
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"encoding/hex"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip32"
+	"testing"
+)
+
+const (
+	// hardhatMnemonic 的前几个地址是hardhat和anvil默认账户，公开的测试向量
+	hardhatMnemonic = "test test test test test test test test test test test junk"
+	// bip39Mnemonic BIP-39官方测试向量中的助记词
+	bip39Mnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+)
+
+func TestHDPath(t *testing.T) {
+	tests := []struct {
+		template string
+		index    uint
+		want     string
+	}{
+		{DefaultHDPath, 7, "m/44'/60'/0'/0/7"},
+		{LedgerLiveHDPath, 3, "m/44'/60'/3'/0/0"},
+		{"m/44'/60'/1'/0/{index}", 0, "m/44'/60'/1'/0/0"},
+	}
+	for _, test := range tests {
+		if got, err := HDPath(test.template, test.index); err != nil || got != test.want {
+			t.Errorf("HDPath(%s, %d) = %s %v, want %s", test.template, test.index, got, err, test.want)
+		}
+	}
+	for _, template := range []string{"44'/60'/0'/0/{index}", "m/44'/60'/0'/0/0", "m/44'/60'/{index}'/0/{index}"} {
+		if _, err := HDPath(template, 0); err == nil {
+			t.Errorf("HDPath(%s) = nil error, want invalid template", template)
+		}
+	}
+}
+
+func TestDerivePrivateKey(t *testing.T) {
+	tests := []struct {
+		template string
+		index    uint
+		want     string
+	}{
+		{DefaultHDPath, 0, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"},
+		{DefaultHDPath, 1, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"},
+		{DefaultHDPath, 2, "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"},
+		// m/44'/60'/0'/0/0与默认路径的第0个账户相同
+		{LedgerLiveHDPath, 0, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"},
+		{"m/44'/60'/0'/0/{index}", 1, "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"},
+	}
+	for _, test := range tests {
+		key, err := DerivePrivateKey(hardhatMnemonic, "", test.template, test.index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := GetAddressFromPrivateKey(key); got != common.HexToAddress(test.want) {
+			t.Errorf("DerivePrivateKey(%s, %d) = %s, want %s", test.template, test.index, got.Hex(), test.want)
+		}
+	}
+
+	key, err := GetPrivateKey(hardhatMnemonic, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(crypto.FromECDSA(key)); got != "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80" {
+		t.Errorf("GetPrivateKey(0) = %s, want the first hardhat key", got)
+	}
+
+	// 自定义模板中的序号换到账户层级后得到其他地址
+	ledger, err := DerivePrivateKey(hardhatMnemonic, "", LedgerLiveHDPath, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explicit, err := DerivePrivateKey(hardhatMnemonic, "", "m/44'/60'/1'/0/{index}", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetAddressFromPrivateKey(ledger) != GetAddressFromPrivateKey(explicit) {
+		t.Error("Ledger Live index 1 differs from m/44'/60'/1'/0/0")
+	}
+	if GetAddressFromPrivateKey(ledger) == common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8") {
+		t.Error("Ledger Live index 1 derived the default path index 1")
+	}
+
+	if _, err := DerivePrivateKey("test test test", "", DefaultHDPath, 0); err == nil {
+		t.Error("DerivePrivateKey of an invalid mnemonic = nil error")
+	}
+}
+
+func TestMasterKeyPassphrase(t *testing.T) {
+	seeds := []struct {
+		passphrase string
+		seed       string
+	}{
+		{"", "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"},
+		{"TREZOR", "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"},
+	}
+	for _, test := range seeds {
+		seed, err := hex.DecodeString(test.seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := bip32.NewMasterKey(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		master, err := MasterKey(bip39Mnemonic, test.passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if master.String() != want.String() {
+			t.Errorf("MasterKey with passphrase %q = %s, want the key of the BIP-39 seed %s", test.passphrase, master, want)
+		}
+	}
+
+	key, err := DerivePrivateKey(bip39Mnemonic, "", DefaultHDPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutPassphrase := GetAddressFromPrivateKey(key)
+	if withoutPassphrase != common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94") {
+		t.Errorf("DerivePrivateKey without passphrase = %s, want 0x9858EfFD232B4033E47d90003D41EC34EcaEda94", withoutPassphrase.Hex())
+	}
+	// 同一个助记词，有无passphrase得到不同的地址
+	key, err = DerivePrivateKey(bip39Mnemonic, "TREZOR", DefaultHDPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if GetAddressFromPrivateKey(key) == withoutPassphrase {
+		t.Error("DerivePrivateKey with a passphrase derived the address without it")
+	}
+	master, err := MasterKey(bip39Mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromMaster, err := DeriveChildKey(master, DefaultHDPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fromMaster.Equal(key) {
+		t.Error("DeriveChildKey of MasterKey differs from DerivePrivateKey")
+	}
+}
@@ -0,0 +1,159 @@
+package wallet
+
+import (
+	"bufio"
+	"cronos-tools/src/utils"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// MnemonicEnv 读取助记词的环境变量
+	MnemonicEnv = "CRONOS_TOOLS_MNEMONIC"
+	// PassphraseEnv 读取keystore和vault密码的环境变量，未设置时提示输入
+	PassphraseEnv = "CRONOS_TOOLS_PASSPHRASE"
+	// BIP39PassphraseEnv 读取助记词BIP-39密码的环境变量
+	BIP39PassphraseEnv = "CRONOS_TOOLS_BIP39_PASSPHRASE"
+)
+
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// KeySource gives the private key of the account at an index.
+type KeySource interface {
+	PrivateKey(index uint) (*ecdsa.PrivateKey, error)
+}
+
+// MnemonicSource derives keys from a BIP-39 mnemonic along a path template.
+type MnemonicSource struct {
+	// master 助记词种子的主密钥，创建时计算一次
+	master *bip32.Key
+	hdPath string
+}
+
+// NewMnemonicSource validates the mnemonic and the path template. An empty hdPath means utils.DefaultHDPath.
+func NewMnemonicSource(mnemonic string, passphrase string, hdPath string) (*MnemonicSource, error) {
+	mnemonic = NormalizeMnemonic(mnemonic)
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+	if hdPath == "" {
+		hdPath = utils.DefaultHDPath
+	}
+	if _, err := utils.HDPath(hdPath, 0); err != nil {
+		return nil, err
+	}
+	master, err := utils.MasterKey(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &MnemonicSource{master: master, hdPath: hdPath}, nil
+}
+
+func (s *MnemonicSource) PrivateKey(index uint) (*ecdsa.PrivateKey, error) {
+	return utils.DeriveChildKey(s.master, s.hdPath, index)
+}
+
+// HDPath is the path template the keys are derived along.
+func (s *MnemonicSource) HDPath() string {
+	return s.hdPath
+}
+
+// Path is the derivation path of the account at index.
+func (s *MnemonicSource) Path(index uint) string {
+	path, _ := utils.HDPath(s.hdPath, index)
+	return path
+}
+
+// NormalizeMnemonic trims the mnemonic and collapses the whitespace between words.
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(mnemonic), " ")
+}
+
+// ReadMnemonicFile reads a mnemonic from path, or from stdin when path is "-".
+func ReadMnemonicFile(path string) (string, error) {
+	var reader io.Reader
+	if path == "-" {
+		reader = os.Stdin
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		reader = file
+	}
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return NormalizeMnemonic(line), nil
+}
+
+// KeystoreSource serves keys from go-ethereum keystore JSON files. Index i is the i-th
+// file in name order, which for files written by geth is creation order.
+type KeystoreSource struct {
+	files      []string
+	passphrase string
+
+	mu   sync.Mutex
+	keys map[uint]*ecdsa.PrivateKey
+}
+
+// NewKeystoreSource loads a single keystore file or every file in a keystore directory.
+func NewKeystoreSource(path string, passphrase string) (*KeystoreSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+		sort.Strings(files)
+	} else {
+		files = []string{path}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no keystore file in %s", path)
+	}
+	return &KeystoreSource{files: files, passphrase: passphrase, keys: make(map[uint]*ecdsa.PrivateKey)}, nil
+}
+
+func (s *KeystoreSource) PrivateKey(index uint) (*ecdsa.PrivateKey, error) {
+	if index >= uint(len(s.files)) {
+		return nil, fmt.Errorf("keystore has %d keys, index %d is out of range", len(s.files), index)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[index]; ok {
+		return key, nil
+	}
+	keyJSON, err := os.ReadFile(s.files[index])
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(keyJSON, s.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("can not decrypt %s: %w", s.files[index], err)
+	}
+	s.keys[index] = key.PrivateKey
+	return key.PrivateKey, nil
+}
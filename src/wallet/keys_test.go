@@ -0,0 +1,63 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeKeystore 把key加密写入dir下的name文件
+func writeKeystore(t *testing.T, dir string, name string, key *ecdsa.PrivateKey, passphrase string) {
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key}, passphrase, keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), keyJSON, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeystoreSource(t *testing.T) {
+	dir := t.TempDir()
+	keys := make([]*ecdsa.PrivateKey, 2)
+	for i := range keys {
+		var err error
+		if keys[i], err = crypto.GenerateKey(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 按文件名排序，隐藏文件被忽略
+	writeKeystore(t, dir, "b", keys[1], "secret")
+	writeKeystore(t, dir, "a", keys[0], "secret")
+	if err := os.WriteFile(filepath.Join(dir, ".DS_Store"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := NewKeystoreSource(dir, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range keys {
+		key, err := source.PrivateKey(uint(i))
+		if err != nil || !key.Equal(want) {
+			t.Errorf("PrivateKey(%d) = %v, want the key of file %d", i, err, i)
+		}
+	}
+	if _, err := source.PrivateKey(2); err == nil {
+		t.Error("PrivateKey(2) of 2 keys returned no error")
+	}
+
+	single, err := NewKeystoreSource(filepath.Join(dir, "b"), "wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := single.PrivateKey(0); err == nil {
+		t.Error("PrivateKey with a wrong passphrase returned no error")
+	}
+	if _, err := NewKeystoreSource(t.TempDir(), "secret"); err == nil {
+		t.Error("NewKeystoreSource of an empty directory returned no error")
+	}
+}
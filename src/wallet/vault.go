@@ -0,0 +1,67 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"os"
+	"path/filepath"
+)
+
+var ErrVaultExists = errors.New("vault already exists")
+
+// vaultFile 本地加密保存的助记词，加密格式与keystore v3相同（scrypt + aes-128-ctr）
+type vaultFile struct {
+	Version int                 `json:"version"`
+	Crypto  keystore.CryptoJSON `json:"crypto"`
+}
+
+// DefaultVaultPath is ~/.cronos-tools/vault.json.
+func DefaultVaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".cronos-tools", "vault.json")
+	}
+	return filepath.Join(home, ".cronos-tools", "vault.json")
+}
+
+// SaveVault encrypts mnemonic with passphrase and writes it to path. It refuses to
+// overwrite an existing vault unless overwrite is set.
+func SaveVault(path string, mnemonic string, passphrase string, overwrite bool) error {
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return ErrVaultExists
+	}
+	mnemonic = NormalizeMnemonic(mnemonic)
+	if _, err := NewMnemonicSource(mnemonic, "", ""); err != nil {
+		return err
+	}
+	cryptoJSON, err := keystore.EncryptDataV3([]byte(mnemonic), []byte(passphrase), keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(vaultFile{Version: 1, Crypto: cryptoJSON}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// OpenVault decrypts the mnemonic stored at path.
+func OpenVault(path string, passphrase string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var vault vaultFile
+	if err := json.Unmarshal(content, &vault); err != nil {
+		return "", err
+	}
+	mnemonic, err := keystore.DecryptDataV3(vault.Crypto, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return string(mnemonic), nil
+}
@@ -0,0 +1,57 @@
+package wallet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir", "vault.json")
+	// 保存时规范化助记词中的空白
+	if err := SaveVault(path, "  abandon abandon abandon abandon abandon abandon\n abandon abandon abandon abandon abandon about ", "secret", false); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("vault mode = %v, want 0600", info.Mode().Perm())
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "abandon") {
+		t.Error("vault contains the mnemonic in plain text")
+	}
+	mnemonic, err := OpenVault(path, "secret")
+	if err != nil || mnemonic != testMnemonic {
+		t.Errorf("OpenVault = %q %v, want the saved mnemonic", mnemonic, err)
+	}
+
+	if _, err := OpenVault(path, "wrong"); err == nil {
+		t.Error("OpenVault with a wrong passphrase returned no error")
+	}
+	if err := SaveVault(path, testMnemonic, "other", false); !errors.Is(err, ErrVaultExists) {
+		t.Errorf("SaveVault over an existing vault = %v, want ErrVaultExists", err)
+	}
+	if mnemonic, err := OpenVault(path, "secret"); err != nil || mnemonic != testMnemonic {
+		t.Errorf("vault changed after a refused save: %q %v", mnemonic, err)
+	}
+}
+
+func TestSaveVaultInvalidMnemonic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	if err := SaveVault(path, "abandon abandon abandon", "secret", false); !errors.Is(err, ErrInvalidMnemonic) {
+		t.Errorf("SaveVault of an invalid mnemonic = %v, want ErrInvalidMnemonic", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("an invalid mnemonic was written to the vault")
+	}
+}